@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+
 	"github.com/epentland/gohandlr/pkg/codegen"
 	"github.com/spf13/cobra"
 )
@@ -8,8 +12,120 @@ import (
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate Go code",
-	Long:  `Generate Go code that uses the library package.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		codegen.GenerateCode(openapiPath)
+	Long: `Generate Go code that uses the library package.
+
+The settings are read from ` + codegen.ConfigFile + ` in the working directory, if
+it exists, or from the file given with --config. Flags override the settings
+of every target. With --openapi only that spec is generated, using the
+top-level settings of the config.`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		targets := config.TargetList()
+		if cmd.Flags().Changed("openapi") {
+			targets = []codegen.Target{config.Target}
+		}
+		if len(targets) > 1 && (cmd.Flags().Changed("output") || cmd.Flags().Changed("package")) {
+			return fmt.Errorf("--output and --package only work with one target")
+		}
+
+		outputs := make(map[string]bool)
+		for i, target := range targets {
+			targets[i] = applyFlags(cmd, target)
+			output := filepath.Clean(targets[i].Output)
+			if targets[i].Output == "" {
+				output = "handlr"
+			}
+			if outputs[output] {
+				return fmt.Errorf("more than one target generates into %s", output)
+			}
+			outputs[output] = true
+		}
+
+		for _, target := range targets {
+			codegen.Generate(target)
+		}
+		return nil
 	},
 }
+
+var (
+	configPath string
+	target     codegen.Target
+	features   struct{ docs, methods, security, rateLimit, client bool }
+)
+
+func init() {
+	flags := generateCmd.Flags()
+	flags.StringVarP(&configPath, "config", "c", "", "Path to the config file (default "+codegen.ConfigFile+" if it exists)")
+	flags.StringVarP(&target.OpenAPI, "openapi", "o", "", "Path to the openapi.yaml file")
+	flags.StringVar(&target.Output, "output", "", "Directory of the generated package (default ./handlr)")
+	flags.StringVar(&target.Package, "package", "", "Name of the generated package (default the base of --output)")
+	flags.StringVar(&target.Module, "module", "", "Import path of the generated package (default from go.mod)")
+	flags.StringVar(&target.Router, "router", "", "Router to register handlers on: chi or stdlib (default chi)")
+	flags.StringVar(&target.Layout, "layout", "", "File layout: split or single (default split)")
+	flags.StringVar(&target.Server, "server", "", "How handlers call the API: functions in process.go or a Server interface (default functions)")
+	flags.BoolVar(&features.docs, "docs", true, "Embed the spec and serve it with gohandlr.WithDocs")
+	flags.BoolVar(&features.methods, "methods", true, "Answer OPTIONS, HEAD and 405 Method Not Allowed for every path")
+	flags.BoolVar(&features.security, "security", true, "Generate an Authenticator from the security schemes")
+	flags.BoolVar(&features.rateLimit, "rate-limit", true, "Apply the x-gohandlr-rate-limit extension")
+	flags.BoolVar(&features.client, "client", true, "Generate a client package in the client directory of the output")
+}
+
+// loadConfig reads --config, or gohandlr.yaml if it exists
+func loadConfig(cmd *cobra.Command) (codegen.Config, error) {
+	path := configPath
+	if path == "" {
+		if _, err := os.Stat(codegen.ConfigFile); err != nil {
+			if !cmd.Flags().Changed("openapi") {
+				return codegen.Config{}, fmt.Errorf("no %s in the working directory, pass --openapi or --config", codegen.ConfigFile)
+			}
+			return codegen.Config{}, nil
+		}
+		path = codegen.ConfigFile
+	}
+	return codegen.LoadConfig(path)
+}
+
+// applyFlags overrides the settings of t with the flags that are set
+func applyFlags(cmd *cobra.Command, t codegen.Target) codegen.Target {
+	changed := cmd.Flags().Changed
+	for _, setting := range []struct {
+		flag      string
+		value     *string
+		flagValue string
+	}{
+		{"openapi", &t.OpenAPI, target.OpenAPI},
+		{"output", &t.Output, target.Output},
+		{"package", &t.Package, target.Package},
+		{"module", &t.Module, target.Module},
+		{"router", &t.Router, target.Router},
+		{"layout", &t.Layout, target.Layout},
+		{"server", &t.Server, target.Server},
+	} {
+		if changed(setting.flag) {
+			*setting.value = setting.flagValue
+		}
+	}
+	for _, feature := range []struct {
+		flag      string
+		value     **bool
+		flagValue bool
+	}{
+		{"docs", &t.Features.Docs, features.docs},
+		{"methods", &t.Features.Methods, features.methods},
+		{"security", &t.Features.Security, features.security},
+		{"rate-limit", &t.Features.RateLimit, features.rateLimit},
+		{"client", &t.Features.Client, features.client},
+	} {
+		if changed(feature.flag) {
+			*feature.value = &feature.flagValue
+		}
+	}
+	return t
+}
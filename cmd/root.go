@@ -7,8 +7,6 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var openapiPath string
-
 var rootCmd = &cobra.Command{
 	Use:   "gohandlr",
 	Short: "A CLI tool for code generation",
@@ -26,10 +24,4 @@ func Execute() {
 func init() {
 	// Add subcommands here
 	rootCmd.AddCommand(generateCmd)
-
-	// Define flags
-	generateCmd.Flags().StringVarP(&openapiPath, "openapi", "o", "", "Path to the openapi.yaml file (required)")
-
-	// Mark flags as required
-	generateCmd.MarkFlagRequired("openapi")
 }
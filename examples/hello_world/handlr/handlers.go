@@ -1,31 +1,28 @@
-package handlr
-
 // Code generated by gohandlr. DO NOT EDIT.
 
+package handlr
+
 import (
 	"fmt"
 	"github.com/epentland/gohandlr/pkg/gohandlr"
 	"github.com/go-chi/chi/v5"
 	"net/http"
-	"strconv"
 )
 
 // PUT request to /users/{id}
 func HandlePutUsersId(options ...gohandlr.Option) (string, string, http.HandlerFunc) {
 
+	options = append([]gohandlr.Option{gohandlr.WithVersion(Version)}, options...)
+
 	paramReader := gohandlr.WithParamsReader(func(r *http.Request, v interface{}) error {
 		req, ok := v.(*PutUsersIdInput)
 		if !ok {
 			return fmt.Errorf("invalid type")
 		}
 
-		Id := chi.URLParam(r, ("id"))
-
-		IdInt, err := strconv.Atoi(Id)
-		if err != nil {
+		if err := gohandlr.ReadPathParam(chi.URLParam(r, "id"), gohandlr.Param{Name: "id", In: "path", Style: "simple", Required: true}, &req.Id); err != nil {
 			return err
 		}
-		req.Id = IdInt
 
 		return nil
 	})
@@ -33,3 +30,25 @@ func HandlePutUsersId(options ...gohandlr.Option) (string, string, http.HandlerF
 
 	return "PUT", "/users/{id}", gohandlr.HandlerWithRequestWithResponse(processPutUsersId, options...)
 }
+
+// registerPaths registers the responders every path of the API needs besides
+// its operations: OPTIONS, HEAD for GET operations, and 405 Method Not Allowed
+// for the other methods, unless the spec defines them. With gohandlr.WithDocs
+// it serves the OpenAPI document and its docs page too.
+func registerPaths(r *chi.Mux, options ...gohandlr.Option) {
+	options = append([]gohandlr.Option{gohandlr.WithVersion(Version)}, options...)
+	if gohandlr.NewConfig(options...).Docs {
+		r.MethodFunc("GET", "/openapi.json", OpenAPI.JSONHandler(options...))
+		r.MethodFunc("GET", "/openapi.yaml", OpenAPI.YAMLHandler(options...))
+		r.MethodFunc("GET", "/docs", OpenAPI.DocsHandler(options...))
+	}
+
+	r.MethodFunc("OPTIONS", "/users/{id}", gohandlr.OptionsHandler([]string{"PUT"}, options...))
+	r.MethodFunc("CONNECT", "/users/{id}", gohandlr.MethodNotAllowedHandler([]string{"PUT"}, options...))
+	r.MethodFunc("DELETE", "/users/{id}", gohandlr.MethodNotAllowedHandler([]string{"PUT"}, options...))
+	r.MethodFunc("GET", "/users/{id}", gohandlr.MethodNotAllowedHandler([]string{"PUT"}, options...))
+	r.MethodFunc("HEAD", "/users/{id}", gohandlr.MethodNotAllowedHandler([]string{"PUT"}, options...))
+	r.MethodFunc("PATCH", "/users/{id}", gohandlr.MethodNotAllowedHandler([]string{"PUT"}, options...))
+	r.MethodFunc("POST", "/users/{id}", gohandlr.MethodNotAllowedHandler([]string{"PUT"}, options...))
+	r.MethodFunc("TRACE", "/users/{id}", gohandlr.MethodNotAllowedHandler([]string{"PUT"}, options...))
+}
@@ -6,6 +6,8 @@ import (
 )
 
 func RegisterHandlers(r *chi.Mux) {
+	registerPaths(r)
+
 	r.MethodFunc(HandlePutUsersId())
 
 }
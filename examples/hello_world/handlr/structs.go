@@ -1,4 +1,5 @@
 // Code generated by gohandlr. DO NOT EDIT.
+
 package handlr
 
 type PutUsersIdInput struct {
@@ -7,19 +8,19 @@ type PutUsersIdInput struct {
 }
 
 type Address struct {
-	City       string `json:"city"`
-	PostalCode string `json:"postalCode"`
-	Street     string `json:"street"`
+	Street     *string `json:"street,omitempty"`
+	City       *string `json:"city,omitempty"`
+	PostalCode *string `json:"postalCode,omitempty"`
 }
 
-type Company struct {
-	Employees []User `json:"employees"`
-	Name      string `json:"name"`
+type User struct {
+	Id      int      `json:"id"`
+	Name    string   `json:"name"`
+	Email   *string  `json:"email,omitempty"`
+	Address *Address `json:"address,omitempty"`
 }
 
-type User struct {
-	Address Address `json:"address"`
-	Email   string  `json:"email"`
-	Id      int     `json:"id"`
-	Name    string  `json:"name"`
+type Company struct {
+	Name      string `json:"name"`
+	Employees []User `json:"employees,omitempty"`
 }
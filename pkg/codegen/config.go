@@ -0,0 +1,280 @@
+package codegen
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile is the config file the generate command picks up from the working directory
+const ConfigFile = "gohandlr.yaml"
+
+// Routers the generated code can register its handlers on
+const (
+	RouterChi    = "chi"
+	RouterStdlib = "stdlib"
+)
+
+// File layouts of the generated code
+const (
+	// LayoutSplit generates a file per concern: structs.go, handlers.go, ...
+	LayoutSplit = "split"
+	// LayoutSingle generates everything into one <package>_gen.go
+	LayoutSingle = "single"
+)
+
+// Ways the generated handlers call the code of the API
+const (
+	// ServerFunctions calls a process function per operation, which the
+	// user writes in process.go
+	ServerFunctions = "functions"
+	// ServerInterface calls the methods of an implementation of the
+	// generated Server interface, which RegisterHandlers takes
+	ServerInterface = "interface"
+)
+
+// Config is the content of gohandlr.yaml. The top-level settings apply to
+// every target that doesn't set them itself:
+//
+//	router: chi
+//	targets:
+//	  - openapi: users.yaml
+//	    output: ./users
+//	  - openapi: billing.yaml
+//	    output: ./internal/billing
+//	    features:
+//	      docs: false
+type Config struct {
+	Target  `yaml:",inline"`
+	Targets []Target `yaml:"targets"`
+}
+
+// Target is an OpenAPI spec and the package generated from it
+type Target struct {
+	// OpenAPI is the path of the spec
+	OpenAPI string `yaml:"openapi"`
+	// Output is the directory of the generated package, ./handlr by default
+	Output string `yaml:"output"`
+	// Package is the name of the generated package, the base of Output by default
+	Package string `yaml:"package"`
+	// Module is the import path of the generated package, worked out from
+	// the go.mod of Output by default
+	Module string `yaml:"module"`
+	// Router is chi or stdlib, the net/http ServeMux of Go 1.22
+	Router string `yaml:"router"`
+	// Layout is split or single
+	Layout string `yaml:"layout"`
+	// Server is functions or interface
+	Server   string   `yaml:"server"`
+	Features Features `yaml:"features"`
+}
+
+// Features toggles parts of the generated code. They are all on by default.
+type Features struct {
+	// Docs embeds the spec, serves it with gohandlr.WithDocs and publishes its version
+	Docs *bool `yaml:"docs"`
+	// Methods answers OPTIONS, HEAD and 405 Method Not Allowed for every path
+	Methods *bool `yaml:"methods"`
+	// Security generates an Authenticator from the security schemes
+	Security *bool `yaml:"security"`
+	// RateLimit applies the x-gohandlr-rate-limit extension
+	RateLimit *bool `yaml:"rateLimit"`
+	// Client generates a client package in the client directory of Output
+	Client *bool `yaml:"client"`
+}
+
+// LoadConfig reads a config file. Relative paths in it are relative to the
+// directory of the file.
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	config.Target.relativeTo(dir)
+	for i := range config.Targets {
+		config.Targets[i].relativeTo(dir)
+	}
+	return config, nil
+}
+
+// TargetList returns the targets of the config with the top-level settings
+// applied, or the top-level target when there are no targets
+func (c Config) TargetList() []Target {
+	if len(c.Targets) == 0 {
+		return []Target{c.Target}
+	}
+	targets := make([]Target, 0, len(c.Targets))
+	for _, target := range c.Targets {
+		targets = append(targets, target.Inherit(c.Target))
+	}
+	return targets
+}
+
+// Inherit fills the settings t doesn't set from defaults. Output, Package and
+// Module are never inherited, so targets don't generate over each other.
+func (t Target) Inherit(defaults Target) Target {
+	if t.OpenAPI == "" {
+		t.OpenAPI = defaults.OpenAPI
+	}
+	if t.Router == "" {
+		t.Router = defaults.Router
+	}
+	if t.Layout == "" {
+		t.Layout = defaults.Layout
+	}
+	if t.Server == "" {
+		t.Server = defaults.Server
+	}
+	t.Features = t.Features.Inherit(defaults.Features)
+	return t
+}
+
+// Inherit fills the features f doesn't set from defaults
+func (f Features) Inherit(defaults Features) Features {
+	for _, feature := range []struct{ value, fallback **bool }{
+		{&f.Docs, &defaults.Docs},
+		{&f.Methods, &defaults.Methods},
+		{&f.Security, &defaults.Security},
+		{&f.RateLimit, &defaults.RateLimit},
+		{&f.Client, &defaults.Client},
+	} {
+		if *feature.value == nil {
+			*feature.value = *feature.fallback
+		}
+	}
+	return f
+}
+
+func (f Features) docs() bool      { return enabled(f.Docs) }
+func (f Features) methods() bool   { return enabled(f.Methods) }
+func (f Features) security() bool  { return enabled(f.Security) }
+func (f Features) rateLimit() bool { return enabled(f.RateLimit) }
+func (f Features) client() bool    { return enabled(f.Client) }
+
+func enabled(feature *bool) bool {
+	return feature == nil || *feature
+}
+
+func (t *Target) relativeTo(dir string) {
+	if t.OpenAPI != "" && !filepath.IsAbs(t.OpenAPI) {
+		t.OpenAPI = filepath.Join(dir, t.OpenAPI)
+	}
+	if t.Output != "" && !filepath.IsAbs(t.Output) {
+		t.Output = filepath.Join(dir, t.Output)
+	}
+}
+
+// resolve fills in the defaults of t and checks its settings
+func (t Target) resolve() (Target, error) {
+	if t.OpenAPI == "" {
+		return t, errors.New("no OpenAPI spec to generate from")
+	}
+	if t.Output == "" {
+		t.Output = "handlr"
+	}
+	t.Output = filepath.Clean(t.Output)
+	if t.Package == "" {
+		t.Package = strings.ToLower(strings.NewReplacer("-", "", "_", "", ".", "").Replace(filepath.Base(t.Output)))
+	}
+	if !token.IsIdentifier(t.Package) || token.IsKeyword(t.Package) {
+		return t, fmt.Errorf("%s: invalid package name %q", t.Output, t.Package)
+	}
+	if t.Module == "" {
+		module, err := importPath(t.Output)
+		if err != nil {
+			return t, err
+		}
+		t.Module = module
+	}
+
+	switch t.Router {
+	case "":
+		t.Router = RouterChi
+	case RouterChi, RouterStdlib:
+	default:
+		return t, fmt.Errorf("%s: unknown router %q, expected %s or %s", t.Output, t.Router, RouterChi, RouterStdlib)
+	}
+	switch t.Layout {
+	case "":
+		t.Layout = LayoutSplit
+	case LayoutSplit, LayoutSingle:
+	default:
+		return t, fmt.Errorf("%s: unknown layout %q, expected %s or %s", t.Output, t.Layout, LayoutSplit, LayoutSingle)
+	}
+	switch t.Server {
+	case "":
+		t.Server = ServerFunctions
+	case ServerFunctions, ServerInterface:
+	default:
+		return t, fmt.Errorf("%s: unknown server %q, expected %s or %s", t.Output, t.Server, ServerFunctions, ServerInterface)
+	}
+	return t, nil
+}
+
+// importPath works out the import path of dir from the go.mod above it. It
+// is empty when dir isn't in a module.
+func importPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		module, err := modulePath(filepath.Join(root, "go.mod"))
+		if err != nil {
+			return "", err
+		}
+		if module != "" {
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", err
+			}
+			if rel == "." {
+				return module, nil
+			}
+			return module + "/" + filepath.ToSlash(rel), nil
+		}
+		if filepath.Dir(root) == root {
+			return "", nil
+		}
+	}
+}
+
+// modulePath reads the module path of a go.mod file, if it exists
+func modulePath(goMod string) (string, error) {
+	file, err := os.Open(goMod)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s: no module directive", goMod)
+}
@@ -0,0 +1,56 @@
+package codegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// bundleDocument moves external references into the document and returns it
+// as JSON and YAML, for the generated package to embed
+func bundleDocument(ctx context.Context, doc *openapi3.T) (jsonDoc, yamlDoc []byte, err error) {
+	doc.InternalizeRefs(ctx, nil)
+
+	compact, err := doc.MarshalJSON()
+	if err != nil {
+		return nil, nil, err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, compact, "", "  "); err != nil {
+		return nil, nil, err
+	}
+	indented.WriteByte('\n')
+
+	// JSON is YAML, so decoding it keeps the order of the keys
+	var node yaml.Node
+	if err := yaml.Unmarshal(compact, &node); err != nil {
+		return nil, nil, err
+	}
+	blockStyle(&node)
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, err
+	}
+	return indented.Bytes(), out.Bytes(), nil
+}
+
+// blockStyle turns the flow style of decoded JSON into block style YAML
+func blockStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+		// Quote only the strings YAML would read as something else
+		node.Style &^= yaml.DoubleQuotedStyle
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
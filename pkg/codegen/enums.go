@@ -0,0 +1,110 @@
+package codegen
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Enum is a named type for a schema with enum values
+type Enum struct {
+	Name string
+	// Type is the underlying Go type, like string or int64
+	Type   string
+	Values []EnumValue
+}
+
+// EnumValue is a constant of an Enum
+type EnumValue struct {
+	Name string
+	// Value is the Go literal of the value, like "available" or 2
+	Value string
+}
+
+// isEnumType reports whether values of a Go type can be constants of an Enum
+func isEnumType(typ string) bool {
+	switch typ {
+	case "string", "int", "int32", "int64", "float32", "float64", "bool":
+		return true
+	}
+	return false
+}
+
+// enum returns the named type of an inline enum, defining it the first time
+func (b *typeBuilder) enum(s *openapi3.Schema, name string) string {
+	if named, ok := b.named[s]; ok {
+		return named
+	}
+	name = b.reserve(name)
+	b.defineEnum(s, name)
+	return name
+}
+
+// defineEnum adds the type and constants of an enum schema called name
+func (b *typeBuilder) defineEnum(s *openapi3.Schema, name string) {
+	b.named[s] = name
+	enum := Enum{Name: name, Type: baseType(s)}
+
+	varNames, err := getEnumVarNames(s)
+	if err != nil {
+		log.Fatalf("Error reading enum %s: %v", name, err)
+	}
+
+	for i, value := range s.Enum {
+		// null is allowed by the pointer of nullable enums
+		if value == nil {
+			continue
+		}
+		literal, suffix, err := enumLiteral(enum.Type, value)
+		if err != nil {
+			log.Fatalf("Error reading enum %s: %v", name, err)
+		}
+
+		var constName string
+		if varNames != nil {
+			constName = varNames[i]
+			if b.names[constName] {
+				log.Fatalf("Error reading enum %s: %s is already declared", name, constName)
+			}
+			b.names[constName] = true
+		} else {
+			constName = b.reserve(name + suffix)
+		}
+		enum.Values = append(enum.Values, EnumValue{Name: constName, Value: literal})
+	}
+	b.enums = append(b.enums, enum)
+}
+
+// enumLiteral returns the Go literal of an enum value and the suffix of its
+// constant name
+func enumLiteral(typ string, value any) (literal, suffix string, err error) {
+	switch v := value.(type) {
+	case string:
+		if typ != "string" {
+			break
+		}
+		suffix = identifierWords(v)
+		if suffix == "" {
+			suffix = "Empty"
+		}
+		return strconv.Quote(v), suffix, nil
+	case float64:
+		isInt := strings.HasPrefix(typ, "int")
+		if !isInt && !strings.HasPrefix(typ, "float") || isInt && v != math.Trunc(v) {
+			break
+		}
+		literal = strconv.FormatFloat(v, 'f', -1, 64)
+		return literal, strings.NewReplacer("-", "Minus", ".", "Point").Replace(literal), nil
+	case bool:
+		if typ != "bool" {
+			break
+		}
+		literal = strconv.FormatBool(v)
+		return literal, identifierWords(literal), nil
+	}
+	return "", "", fmt.Errorf("value %v is not a %s", value, typ)
+}
@@ -0,0 +1,138 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// rateLimitExtension is the operation extension that configures rate limiting
+const rateLimitExtension = "x-gohandlr-rate-limit"
+
+// RateLimit holds the Go expressions used to rate limit an endpoint
+type RateLimit struct {
+	Requests int
+	Per      string
+	Burst    int
+	Key      string
+}
+
+// getRateLimit reads the x-gohandlr-rate-limit extension of an operation, like
+//
+//	x-gohandlr-rate-limit:
+//	  requests: 100
+//	  per: 1m
+//	  burst: 20
+//	  key: header:X-API-Key
+//
+// The key is ip, the default, header:<name> or principal, the principal of
+// an authenticated request.
+func getRateLimit(operation *openapi3.Operation) (*RateLimit, error) {
+	value, ok := operation.Extensions[rateLimitExtension]
+	if !ok {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var ext struct {
+		Requests int    `json:"requests"`
+		Per      string `json:"per"`
+		Burst    int    `json:"burst"`
+		Key      string `json:"key"`
+	}
+	if err := json.Unmarshal(raw, &ext); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", rateLimitExtension, err)
+	}
+	if ext.Requests < 1 {
+		return nil, fmt.Errorf("invalid %s: requests must be at least 1", rateLimitExtension)
+	}
+
+	per := time.Second
+	if ext.Per != "" {
+		per, err = time.ParseDuration(ext.Per)
+		if err != nil || per <= 0 {
+			return nil, fmt.Errorf("invalid %s: per must be a positive duration like 1m", rateLimitExtension)
+		}
+	}
+
+	key := "gohandlr.KeyByIP"
+	switch {
+	case ext.Key == "" || ext.Key == "ip":
+	case strings.HasPrefix(ext.Key, "header:"):
+		key = fmt.Sprintf("gohandlr.KeyByHeader(%q)", strings.TrimPrefix(ext.Key, "header:"))
+	case ext.Key == "principal":
+		key = "gohandlr.KeyByPrincipal"
+	default:
+		return nil, fmt.Errorf("invalid %s: unknown key %q, use ip, header:<name> or principal", rateLimitExtension, ext.Key)
+	}
+
+	return &RateLimit{
+		Requests: ext.Requests,
+		Per:      durationExpr(per),
+		Burst:    ext.Burst,
+		Key:      key,
+	}, nil
+}
+
+// enumVarNamesExtension names the constants of an enum schema
+const enumVarNamesExtension = "x-enum-varnames"
+
+// getEnumVarNames reads the x-enum-varnames extension of a schema, which has
+// a constant name for each value of the enum, like
+//
+//	enum: [1, 2]
+//	x-enum-varnames: [PriorityLow, PriorityHigh]
+func getEnumVarNames(schema *openapi3.Schema) ([]string, error) {
+	value, ok := schema.Extensions[enumVarNamesExtension]
+	if !ok {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if err := json.Unmarshal(raw, &names); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", enumVarNamesExtension, err)
+	}
+	if len(names) != len(schema.Enum) {
+		return nil, fmt.Errorf("invalid %s: %d names for %d values", enumVarNamesExtension, len(names), len(schema.Enum))
+	}
+	for _, name := range names {
+		if !token.IsIdentifier(name) {
+			return nil, fmt.Errorf("invalid %s: %q is not a Go identifier", enumVarNamesExtension, name)
+		}
+	}
+	return names, nil
+}
+
+// durationExpr writes a duration as a Go expression, like 30 * time.Second
+func durationExpr(d time.Duration) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			if d == u.unit {
+				return u.name
+			}
+			return fmt.Sprintf("%d * %s", d/u.unit, u.name)
+		}
+	}
+	return fmt.Sprintf("%d * time.Nanosecond", d)
+}
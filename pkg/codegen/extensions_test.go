@@ -0,0 +1,40 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestGetRateLimitKey(t *testing.T) {
+	tests := []struct {
+		key      any
+		expected string
+	}{
+		{nil, "gohandlr.KeyByIP"},
+		{"ip", "gohandlr.KeyByIP"},
+		{"header:X-API-Key", `gohandlr.KeyByHeader("X-API-Key")`},
+		{"principal", "gohandlr.KeyByPrincipal"},
+	}
+	for _, test := range tests {
+		ext := map[string]any{"requests": 10}
+		if test.key != nil {
+			ext["key"] = test.key
+		}
+		operation := &openapi3.Operation{Extensions: map[string]any{rateLimitExtension: ext}}
+		rateLimit, err := getRateLimit(operation)
+		if err != nil {
+			t.Errorf("Failed to read key %v: %v", test.key, err)
+			continue
+		}
+		if rateLimit.Key != test.expected {
+			t.Errorf("Expected key: %s, got: %s", test.expected, rateLimit.Key)
+		}
+	}
+
+	operation := &openapi3.Operation{Extensions: map[string]any{rateLimitExtension: map[string]any{"requests": 10, "key": "user"}}}
+	if _, err := getRateLimit(operation); err == nil || !strings.Contains(err.Error(), `unknown key "user"`) {
+		t.Errorf("Expected key user to be unknown, got: %v", err)
+	}
+}
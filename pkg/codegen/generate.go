@@ -3,135 +3,211 @@ package codegen
 import (
 	"bytes"
 	"fmt"
-	"go/ast"
 	"go/format"
+	"go/parser"
+	"go/token"
 	"log"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"text/template"
 
 	views "github.com/epentland/gohandlr/pkg/codegen/templates"
 )
 
-// findAndAddComment finds a function with a specific name and adds a comment above it, overwriting any existing comments.
-func findAndAddComment(f *ast.File, funcName, comment string) bool {
-	found := false
-	ast.Inspect(f, func(n ast.Node) bool {
-		fn, ok := n.(*ast.FuncDecl)
-		if ok && fn.Name.Name == funcName {
-			found = true
-			// Create a new comment group
-			cg := &ast.CommentGroup{
-				List: []*ast.Comment{
-					{
-						Slash: fn.Pos() - 1, // Place the comment just before the function
-						Text:  comment,
-					},
-				},
-			}
-
-			// Loop through the existing comments to see if there's already a comment group
-			if f.Comments != nil {
-				for i, c := range f.Comments {
-					if c.End() == fn.Pos()-1 {
-						// Overwrite the existing comment group
-						f.Comments[i] = cg
-						return false // stop the traversal
-					}
-				}
-			} else {
-				// Add the new comment group
-				f.Comments = append(f.Comments, cg)
-			}
-			return false // stop the traversal
-		}
-		return true
-	})
-	return found
+// generatedFiles are the templates of the split layout and the files they generate
+var generatedFiles = []struct{ template, file string }{
+	{"structs", "structs.go"},
+	{"handlers", "handlers.go"},
+	{"document", "document.go"},
+	{"security", "security.go"},
+	{"server", "server.go"},
 }
 
-func addHandlerFunction(content string, endpoint Endpoint, tmpl *template.Template) string {
-	functionName := "process" + endpoint.OperationID
-	fmt.Println(functionName)
-	if !strings.Contains(content, "func "+functionName) {
-		buf := &bytes.Buffer{}
-		fmt.Println("adding function:", functionName)
-		err := tmpl.ExecuteTemplate(buf, "ProcessEndpoint", endpoint)
-		if err != nil {
-			log.Fatalf("Error executing template: %v", err)
+func generateStructs(openAPIStructs OpenAPIStructs, target Target) {
+	tmpl := parseTemplates(target)
+
+	var templates []string
+	for _, generated := range generatedFiles {
+		switch {
+		case generated.template == "document" && !target.Features.docs(),
+			generated.template == "security" && len(openAPIStructs.SecuritySchemes) == 0,
+			generated.template == "server" && target.Server != ServerInterface:
+			removeGenerated(filepath.Join(target.Output, generated.file))
+			continue
 		}
-
-		content += "\n" + buf.String()
-		fmt.Println("adding new content", buf.String())
+		templates = append(templates, generated.template)
 	}
-	return content
-}
 
-// addHandlerToRegisterHandlers reads the RegisterHandlers function as a string and appends the handler call if it doesn't exist
-func addHandlerToRegisterHandlers(content, handlerName string) string {
-	registerFuncStart := "func RegisterHandlers(r *chi.Mux) {"
-	handlerCall := fmt.Sprintf("	r.MethodFunc(%s())\n", handlerName)
-
-	// Check if the handlerName exists in the content without considering the parameters
-	if !strings.Contains(content, handlerName) {
-		content = strings.Replace(content, registerFuncStart, registerFuncStart+"\n"+handlerCall, 1)
+	singleFile := filepath.Join(target.Output, target.Package+"_gen.go")
+	if target.Layout == LayoutSingle {
+		for _, generated := range generatedFiles {
+			removeGenerated(filepath.Join(target.Output, generated.file))
+		}
+		generateSingleFile(tmpl, templates, singleFile, openAPIStructs)
+	} else {
+		removeGenerated(singleFile)
+		for _, generated := range generatedFiles {
+			if slices.Contains(templates, generated.template) {
+				generateFile(tmpl, generated.template, filepath.Join(target.Output, generated.file), openAPIStructs)
+			}
+		}
 	}
 
-	return content
-}
+	generateClient(tmpl, openAPIStructs, target)
 
-func generateStructs(openAPIStructs OpenAPIStructs, packageName string) {
-	tmpl := parseTemplates()
+	processFile := filepath.Join(target.Output, "process.go")
 
-	generateFile(tmpl, "structs", "./"+packageName+"/structs.go", openAPIStructs)
-	generateFile(tmpl, "handlers", "./"+packageName+"/handlers.go", openAPIStructs)
+	// A Server implementation replaces the process functions
+	if target.Server == ServerInterface {
+		if _, err := os.Stat(processFile); err == nil {
+			log.Printf("Warning: %s: the handlers call a Server now, move the process functions to its methods and remove the file", processFile)
+		}
+		return
+	}
 
 	// If process.go does not exist
-	if _, err := os.Stat("./" + packageName + "/process.go"); os.IsNotExist(err) {
-		generateFile(tmpl, "process", "./"+packageName+"/process.go", openAPIStructs)
+	if _, err := os.Stat(processFile); os.IsNotExist(err) {
+		generateFile(tmpl, "process", processFile, openAPIStructs)
 		return
 	}
 
-	// Read the existing file content
-	existingContent, err := os.ReadFile("./" + packageName + "/process.go")
+	// The file belongs to the user from now on, so only what is missing
+	// is added to it
+	existing, err := os.ReadFile(processFile)
 	if err != nil {
 		log.Fatalf("Error reading file: %v", err)
 	}
-	contentStr := string(existingContent)
-
-	// Buffer to hold the new content
-	var newContent bytes.Buffer
-	newContent.WriteString(contentStr)
-
-	for _, endpoints := range openAPIStructs.Endpoints {
-		for _, endpoint := range endpoints {
-
-			contentStr = addHandlerFunction(contentStr, endpoint, tmpl)
+	merged, warnings, err := mergeProcess(existing, openAPIStructs, tmpl, target.Router)
+	if err != nil {
+		log.Fatalf("Error merging %s: %v", processFile, err)
+	}
+	for _, warning := range warnings {
+		log.Printf("Warning: %s: %s", processFile, warning)
+	}
+	if err := os.WriteFile(processFile, merged, 0644); err != nil {
+		log.Fatalf("Error writing file %s: %v", processFile, err)
+	}
+}
 
-			// Add the handler to RegisterHandlers if it doesn't exist
-			handlerName := "Handle" + endpoint.OperationID
-			contentStr = addHandlerToRegisterHandlers(contentStr, handlerName)
+// generateClient generates the client package in the client directory of
+// the output. It imports the generated package, so it needs its module.
+func generateClient(tmpl *template.Template, openAPIStructs OpenAPIStructs, target Target) {
+	dir := filepath.Join(target.Output, "client")
+	clientFile := filepath.Join(dir, "client.go")
+	switch {
+	case !target.Features.client():
+		removeGenerated(clientFile)
+		// The directory goes too, unless something else is in it
+		os.Remove(dir)
+	case target.Module == "":
+		log.Printf("Warning: %s isn't in a Go module, set the module of the target to generate its client", target.Output)
+	default:
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Fatalf("Error creating directory: %v", err)
 		}
+		generateFile(tmpl, "client", clientFile, openAPIStructs)
 	}
+}
 
-	// Format the new content
-	formattedContent, err := format.Source([]byte(contentStr))
-	if err != nil {
-		fmt.Println("Error formatting content:", err)
+// generatedHeader marks the files gohandlr generates
+const generatedHeader = "// Code generated by gohandlr. DO NOT EDIT."
+
+// removeGenerated removes a file left over from an earlier generation. Files
+// without the generated header are kept, as they aren't ours.
+func removeGenerated(fileName string) {
+	content, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
 		return
 	}
-
-	// Write the formatted content to the file
-	err = os.WriteFile("./"+packageName+"/process.go", formattedContent, 0644)
 	if err != nil {
-		fmt.Println("Error writing to file:", err)
+		log.Fatalf("Error reading file %s: %v", fileName, err)
+	}
+	if !bytes.Contains(content, []byte(generatedHeader)) {
 		return
 	}
+	removeFile(fileName)
 }
 
-func parseTemplates() *template.Template {
+func removeFile(fileName string) {
+	if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+		log.Fatalf("Error removing file %s: %v", fileName, err)
+	}
+}
+
+// generateSingleFile executes templates into one file with a shared import block
+func generateSingleFile(tmpl *template.Template, templates []string, fileName string, data interface{}) {
+	var packageName string
+	var imports []string
+	var body bytes.Buffer
+	for _, templateName := range templates {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, templateName, data); err != nil {
+			log.Fatalf("Error executing template %s: %v", templateName, err)
+		}
+
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, templateName, buf.Bytes(), parser.ImportsOnly)
+		if err != nil {
+			log.Fatalf("Error parsing template %s: %v", templateName, err)
+		}
+		packageName = f.Name.Name
+		for _, spec := range f.Imports {
+			imp := spec.Path.Value
+			if spec.Name != nil {
+				imp = spec.Name.Name + " " + imp
+			}
+			if !slices.Contains(imports, imp) {
+				imports = append(imports, imp)
+			}
+		}
+
+		// The declarations start after the imports, which are all the
+		// declarations parsed, or else after the package clause
+		end := f.Name.End()
+		if len(f.Decls) > 0 {
+			end = f.Decls[len(f.Decls)-1].End()
+		}
+		body.Write(buf.Bytes()[fset.Position(end).Offset:])
+		body.WriteString("\n")
+	}
+	// Standard library imports go first, as goimports groups them
+	sort.Slice(imports, func(i, j int) bool {
+		if std := isStdImport(imports[i]); std != isStdImport(imports[j]) {
+			return std
+		}
+		return importSpecPath(imports[i]) < importSpecPath(imports[j])
+	})
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n\npackage %s\n\nimport (\n", generatedHeader, packageName)
+	for i, imp := range imports {
+		if i > 0 && isStdImport(imports[i-1]) && !isStdImport(imp) {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "\t%s\n", imp)
+	}
+	buf.WriteString(")\n")
+	buf.Write(body.Bytes())
+	writeFormatted(fileName, buf.Bytes())
+}
+
+// importSpecPath is the quoted path of an import spec like `name "path"`
+func importSpecPath(spec string) string {
+	return spec[strings.Index(spec, `"`):]
+}
+
+// isStdImport reports whether an import spec imports the standard library
+func isStdImport(spec string) bool {
+	first, _, _ := strings.Cut(strings.Trim(importSpecPath(spec), `"`), "/")
+	return !strings.Contains(first, ".")
+}
+
+func parseTemplates(target Target) *template.Template {
 	templatesFs := views.FS
-	tmpl, err := template.New("struct").Funcs(funcMap).ParseFS(templatesFs, "*.tmpl")
+	tmpl, err := template.New("struct").Funcs(funcMap).Funcs(routerFuncs(target.Router)).ParseFS(templatesFs, "*.tmpl")
 	if err != nil {
 		log.Fatalf("Error parsing template: %v", err)
 	}
@@ -139,24 +215,21 @@ func parseTemplates() *template.Template {
 }
 
 func generateFile(tmpl *template.Template, templateName, fileName string, data interface{}) {
-	file, err := os.Create(fileName)
-	if err != nil {
-		log.Fatalf("Error creating file %s: %v", fileName, err)
-	}
-	defer file.Close()
-
 	var buf bytes.Buffer
-	err = tmpl.ExecuteTemplate(&buf, templateName, data)
+	err := tmpl.ExecuteTemplate(&buf, templateName, data)
 	if err != nil {
 		log.Fatalf("Error executing template %s: %v", templateName, err)
 	}
+	writeFormatted(fileName, buf.Bytes())
+}
 
-	formatted, err := format.Source(buf.Bytes())
+func writeFormatted(fileName string, src []byte) {
+	formatted, err := format.Source(src)
 	if err != nil {
 		log.Fatalf("Error formatting file %s: %v", fileName, err)
 	}
 
-	_, err = file.Write(formatted)
+	err = os.WriteFile(fileName, formatted, 0644)
 	if err != nil {
 		log.Fatalf("Error writing formatted file %s: %v", fileName, err)
 	}
@@ -0,0 +1,272 @@
+package codegen
+
+import (
+	"bytes"
+	"flag"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// generateTarget generates a target into a new directory and returns the
+// generated files by their path in it
+func generateTarget(t *testing.T, target Target) map[string][]byte {
+	t.Helper()
+	target.Output = filepath.Join(t.TempDir(), "api")
+	// The client needs the import path of the package
+	if target.Module == "" {
+		target.Module = "example.com/api"
+	}
+	Generate(target)
+
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(target.Output, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name, _ := filepath.Rel(target.Output, path)
+		files[filepath.ToSlash(name)] = content
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to read the generated package: %v", err)
+	}
+	return files
+}
+
+func TestGenerateGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   string
+		target Target
+	}{
+		{"petstore", "petstore", Target{}},
+		{"petstore_stdlib_single", "petstore", Target{Router: RouterStdlib, Layout: LayoutSingle}},
+		{"shapes", "shapes", Target{}},
+		{"shapes_server", "shapes", Target{Server: ServerInterface}},
+		{"methods", "methods", Target{Router: RouterStdlib, Layout: LayoutSingle}},
+		{"methods_chi", "methods", Target{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.target.OpenAPI = filepath.Join("testdata", test.spec, "openapi.yaml")
+			files := generateTarget(t, test.target)
+
+			golden := filepath.Join("testdata", test.name, "golden")
+			if *update {
+				if err := os.RemoveAll(golden); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.MkdirAll(golden, 0755); err != nil {
+					t.Fatal(err)
+				}
+				for name, content := range files {
+					file := filepath.Join(golden, filepath.FromSlash(name)+".golden")
+					if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(file, content, 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			var expected []string
+			err := filepath.WalkDir(golden, func(path string, entry fs.DirEntry, err error) error {
+				if err != nil || entry.IsDir() {
+					return err
+				}
+				rel, _ := filepath.Rel(golden, path)
+				name := strings.TrimSuffix(filepath.ToSlash(rel), ".golden")
+				expected = append(expected, name)
+
+				want, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				if got, ok := files[name]; !ok {
+					t.Errorf("Expected %s to be generated", name)
+				} else if !bytes.Equal(got, want) {
+					t.Errorf("Generated %s differs from %s, run go test with -update if the change is intended", name, rel)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to read the golden files, run go test with -update to create them: %v", err)
+			}
+			for name := range files {
+				if !slices.Contains(expected, name) {
+					t.Errorf("Expected no %s to be generated", name)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateDeterministic(t *testing.T) {
+	target := Target{OpenAPI: filepath.Join("testdata", "petstore", "openapi.yaml")}
+	first := generateTarget(t, target)
+
+	for i := 0; i < 10; i++ {
+		files := generateTarget(t, target)
+		if len(files) != len(first) {
+			t.Fatalf("Expected %d files, got: %d", len(first), len(files))
+		}
+		for name, content := range files {
+			if !bytes.Equal(content, first[name]) {
+				t.Fatalf("Expected %s to be the same on every run", name)
+			}
+		}
+	}
+}
+
+// TestGenerateHostileNames generates a spec full of names that aren't Go
+// identifiers, keywords and names that collide, checks the package compiles
+// and runs testdata/hostile/json_test.go.txt to round-trip JSON under the
+// names of the spec
+func TestGenerateHostileNames(t *testing.T) {
+	target := Target{OpenAPI: filepath.Join("testdata", "hostile", "openapi.yaml")}
+	vetGenerated(t, target)
+
+	goTool := lookGo(t)
+	output := generateInModule(t, target)
+	module, err := importPath(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test, err := os.ReadFile(filepath.Join("testdata", "hostile", "json_test.go.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	test = bytes.ReplaceAll(test, []byte("example.com/api"), []byte(module))
+	if err := os.WriteFile(filepath.Join(output, "json_test.go"), test, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(goTool, "test", "./"+filepath.ToSlash(output))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Expected JSON to round-trip: %v\n%s", err, output)
+	}
+}
+
+// TestGenerateShapes checks that an operation with each combination of
+// parameters, request body and response compiles with every router, whether
+// it calls a process function or a Server
+func TestGenerateShapes(t *testing.T) {
+	layouts := map[string]string{RouterChi: LayoutSplit, RouterStdlib: LayoutSingle}
+	for _, router := range []string{RouterChi, RouterStdlib} {
+		for _, server := range []string{ServerFunctions, ServerInterface} {
+			t.Run(router+"_"+server, func(t *testing.T) {
+				vetGenerated(t, Target{OpenAPI: filepath.Join("testdata", "shapes", "openapi.yaml"), Router: router, Server: server, Layout: layouts[router]})
+			})
+		}
+	}
+}
+
+// vetGenerated generates a target and checks the package and its client
+// compile
+func vetGenerated(t *testing.T, target Target) {
+	t.Helper()
+	goTool := lookGo(t)
+	output := generateInModule(t, target)
+
+	cmd := exec.Command(goTool, "vet", "./"+filepath.ToSlash(output)+"/...")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Expected the generated package to compile: %v\n%s", err, output)
+	}
+}
+
+// TestGenerateClient runs the generated client against the generated Server
+// of the shapes spec, with the test in testdata/shapes/client_test.go.txt
+func TestGenerateClient(t *testing.T) {
+	goTool := lookGo(t)
+	target := Target{OpenAPI: filepath.Join("testdata", "shapes", "openapi.yaml"), Server: ServerInterface}
+	output := generateInModule(t, target)
+
+	module, err := importPath(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test, err := os.ReadFile(filepath.Join("testdata", "shapes", "client_test.go.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	test = bytes.ReplaceAll(test, []byte("example.com/api"), []byte(module))
+	if err := os.WriteFile(filepath.Join(output, "client", "client_test.go"), test, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(goTool, "test", "./"+filepath.ToSlash(output)+"/client")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Expected the client to pass its test: %v\n%s", err, output)
+	}
+}
+
+// TestGenerateMethods runs testdata/methods/methods_test.go.txt against a
+// spec that defines OPTIONS and HEAD operations, with every router
+func TestGenerateMethods(t *testing.T) {
+	goTool := lookGo(t)
+	routers := map[string]string{
+		RouterChi:    "package api_test\n\nimport \"github.com/go-chi/chi/v5\"\n\nfunc newRouter() *chi.Mux { return chi.NewRouter() }\n",
+		RouterStdlib: "package api_test\n\nimport \"net/http\"\n\nfunc newRouter() *http.ServeMux { return http.NewServeMux() }\n",
+	}
+	for _, router := range []string{RouterChi, RouterStdlib} {
+		t.Run(router, func(t *testing.T) {
+			output := generateInModule(t, Target{OpenAPI: filepath.Join("testdata", "methods", "openapi.yaml"), Router: router})
+			module, err := importPath(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test, err := os.ReadFile(filepath.Join("testdata", "methods", "methods_test.go.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			test = bytes.ReplaceAll(test, []byte("example.com/api"), []byte(module))
+			if err := os.WriteFile(filepath.Join(output, "methods_test.go"), test, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(output, "router_test.go"), []byte(routers[router]), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := exec.Command(goTool, "test", "./"+filepath.ToSlash(output))
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("Expected the spec operations to answer OPTIONS and HEAD: %v\n%s", err, output)
+			}
+		})
+	}
+}
+
+// generateInModule generates a target inside the module, so that it can
+// import gohandlr, and returns its output directory
+func generateInModule(t *testing.T, target Target) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("testdata", "generated-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	target.Output = filepath.Join(dir, "api")
+	Generate(target)
+	return target.Output
+}
+
+func lookGo(t *testing.T) string {
+	t.Helper()
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("The go command is needed to compile the generated package")
+	}
+	return goTool
+}
@@ -2,24 +2,54 @@ package codegen
 
 import (
 	"log"
+	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 
 	"github.com/getkin/kin-openapi/openapi3"
-	"github.com/jinzhu/inflection"
 )
 
 type Parameter struct {
-	Name string
-	Type string
-	Tag  string
+	Name     string
+	Type     string
+	Tag      string
+	Required bool
+	// Style and Explode are how the value is serialized, and Default the
+	// JSON of the value of a missing parameter, if any
+	Style   string
+	Explode bool
+	Default string
+	// GoName is the field of the parameter in the input struct
+	GoName    string
+	StructTag string
 }
 
 type RequestBody struct {
 	Name   string
-	Fields map[string]string
+	Fields []Field
+}
+
+// Field is a property of a schema
+type Field struct {
+	Name string
+	// GoName is the name of the field, unique in its struct
+	GoName string
+	Type   string
+	// OmitEmpty leaves the property out of JSON when it isn't set
+	OmitEmpty bool
+	StructTag string
+}
+
+// Tagless reports whether the name of the property can't be a json tag, so
+// the MarshalJSON and UnmarshalJSON of the struct write and read it
+func (f Field) Tagless() bool {
+	return !validJSONName(f.Name)
 }
 
 type Endpoint struct {
@@ -30,36 +60,107 @@ type Endpoint struct {
 	Body        *RequestBody
 	State       int
 	Response    *RequestBody // Add this field to handle response
+	// Variants are the success responses of an operation with several,
+	// whose Response is the interface they implement
+	Variants []ResponseVariant
+	// Errors are the 4XX, 5XX and default responses with a JSON body
+	Errors    []ErrorResponse
+	RateLimit *RateLimit
+	Security  []string
+	// Versioned publishes the version of the spec in responses
+	Versioned bool
+	// Server calls the method of a Server instead of a process function
+	Server bool
+	// Current looks up the entity tag of the resource with a Current hook,
+	// which If-Match is checked against before the operation runs. PUT,
+	// PATCH and DELETE operations on a path with a GET have it.
+	Current bool
+}
+
+// ErrorResponse is an error response of an operation with a body, generated
+// as a type that implements gohandlr.BodyError
+type ErrorResponse struct {
+	Name string
+	// Status is the status code of the response, 0 for a range or default
+	Status int
+	// Range is the first digit of a range response like 4XX, 0 otherwise
+	Range int
+	// Body is the Go type of the body
+	Body string
+}
+
+// DefaultStatus is the status of a range or default error type whose
+// StatusCode isn't set: 400 for 4XX, and 500 for 5XX and default
+func (e ErrorResponse) DefaultStatus() int {
+	if e.Range == 4 {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
 }
 
 type Component struct {
 	Name   string
-	Fields map[string]string
+	Fields []Field
+	// Additional is the Go type of the additionalProperties of an object
+	// with fixed properties too, kept in its AdditionalProperties map
+	Additional string
+}
+
+// TaglessFields returns the fields whose property names can't be json tags
+func (c Component) TaglessFields() []Field {
+	var fields []Field
+	for _, f := range c.Fields {
+		if f.Tagless() {
+			fields = append(fields, f)
+		}
+	}
+	return fields
 }
 
 type OpenAPIStructs struct {
-	Endpoints  map[string][]Endpoint
-	Components []Component
+	Endpoints       map[string][]Endpoint
+	Components      []Component
+	Enums           []Enum
+	Unions          []Union
+	SecuritySchemes []SecurityScheme
+	// Title and Version are from the info of the spec
+	Title   string
+	Version string
+	// Package, Module, Router, Server and Features are from the target
+	Package  string
+	Module   string
+	Router   string
+	Server   string
+	Features Features
 }
 
 var funcMap = template.FuncMap{
-	"ToCamel": toCamel,
 	"ToUpper": toUpper,
+	"Qualify": qualify,
 }
 
+// GenerateCode generates the handlr package in ./handlr from the spec at openapiPath
 func GenerateCode(openapiPath string) {
-	packagePath := "./handlr"
-	packageName := "handlr"
+	Generate(Target{OpenAPI: openapiPath})
+}
+
+// Generate generates the package of a target
+func Generate(target Target) {
+	target, err := target.resolve()
+	if err != nil {
+		log.Fatalf("Error in target: %v", err)
+	}
+
 	// Check if directory exists
-	if _, err := os.Stat(packagePath); os.IsNotExist(err) {
+	if _, err := os.Stat(target.Output); os.IsNotExist(err) {
 		// Create directory
-		errDir := os.MkdirAll(packagePath, 0755)
+		errDir := os.MkdirAll(target.Output, 0755)
 		if errDir != nil {
 			log.Fatalf("Error creating directory: %v", errDir)
 		}
 	}
 	loader := openapi3.NewLoader()
-	doc, err := loader.LoadFromFile(openapiPath)
+	doc, err := loader.LoadFromFile(target.OpenAPI)
 	if err != nil {
 		log.Fatalf("Error loading OpenAPI document: %v", err)
 	}
@@ -69,8 +170,312 @@ func GenerateCode(openapiPath string) {
 		log.Fatalf("Error validating OpenAPI document: %v", err)
 	}
 
-	openAPIStructs := extractEndpointsAndComponents(doc)
-	generateStructs(openAPIStructs, packageName)
+	openAPIStructs := extractEndpointsAndComponents(doc, readDeclarationOrder(target.OpenAPI, doc))
+	openAPIStructs.applyTarget(target)
+
+	documentFiles := []string{"openapi.json", "openapi.yaml"}
+	if target.Features.docs() {
+		jsonDoc, yamlDoc, err := bundleDocument(loader.Context, doc)
+		if err != nil {
+			log.Fatalf("Error bundling OpenAPI document: %v", err)
+		}
+		for i, content := range [][]byte{jsonDoc, yamlDoc} {
+			if err := os.WriteFile(filepath.Join(target.Output, documentFiles[i]), content, 0644); err != nil {
+				log.Fatalf("Error writing file %s: %v", documentFiles[i], err)
+			}
+		}
+	} else {
+		for _, name := range documentFiles {
+			removeFile(filepath.Join(target.Output, name))
+		}
+	}
+
+	generateStructs(openAPIStructs, target)
+}
+
+// applyTarget leaves out the features the target turns off
+func (o *OpenAPIStructs) applyTarget(target Target) {
+	o.Package = target.Package
+	o.Module = target.Module
+	o.Router = target.Router
+	o.Server = target.Server
+	o.Features = target.Features
+	if !target.Features.security() {
+		o.SecuritySchemes = nil
+	}
+	for _, endpoints := range o.Endpoints {
+		for i := range endpoints {
+			if !target.Features.security() {
+				endpoints[i].Security = nil
+			}
+			if !target.Features.rateLimit() {
+				endpoints[i].RateLimit = nil
+			}
+			endpoints[i].Versioned = target.Features.docs()
+			endpoints[i].Server = target.Server == ServerInterface
+		}
+	}
+}
+
+// fileType is the Go type used for binary response bodies
+const fileType = "gohandlr.File"
+
+// UsesFile reports whether any endpoint responds with a binary body
+func (o OpenAPIStructs) UsesFile() bool {
+	for _, endpoints := range o.Endpoints {
+		for _, endpoint := range endpoints {
+			if endpoint.Response != nil && endpoint.Response.Name == fileType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UsesCurrentAdapter reports whether an endpoint without an input looks up
+// its current entity tag, which its handler adapts to WithCurrentETag
+func (o OpenAPIStructs) UsesCurrentAdapter() bool {
+	for _, endpoint := range o.EndpointList() {
+		if endpoint.Current && len(endpoint.Params) == 0 && endpoint.Body == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesParams reports whether any endpoint reads parameters
+func (o OpenAPIStructs) UsesParams() bool {
+	for _, endpoints := range o.Endpoints {
+		for _, endpoint := range endpoints {
+			if len(endpoint.Params) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PathItem is a path of the API with the methods of its operations
+type PathItem struct {
+	Path    string
+	Methods []string
+	// NotAllowed are the methods that get 405 Method Not Allowed
+	NotAllowed []string
+	// HasGet is true when HEAD requests are answered by the GET operation,
+	// which they are unless the spec defines a HEAD operation
+	HasGet bool
+	// AutoOptions is true when OPTIONS requests are answered with the allowed
+	// methods, which they are unless the spec defines an OPTIONS operation
+	AutoOptions bool
+}
+
+// routedMethods are the methods that get 405 Method Not Allowed when a path doesn't define them
+var routedMethods = []string{"CONNECT", "DELETE", "GET", "PATCH", "POST", "PUT", "TRACE"}
+
+// Paths returns every path of the API, sorted, with its methods
+func (o OpenAPIStructs) Paths() []PathItem {
+	methods := make(map[string][]string)
+	for _, endpoints := range o.Endpoints {
+		for _, endpoint := range endpoints {
+			methods[endpoint.Path] = append(methods[endpoint.Path], strings.ToUpper(endpoint.Method))
+		}
+	}
+
+	paths := make([]PathItem, 0, len(methods))
+	for path, m := range methods {
+		sort.Strings(m)
+		item := PathItem{Path: path, Methods: m}
+		for _, method := range routedMethods {
+			if !slices.Contains(m, method) {
+				item.NotAllowed = append(item.NotAllowed, method)
+			}
+		}
+		item.HasGet = slices.Contains(m, "GET") && !slices.Contains(m, "HEAD")
+		item.AutoOptions = !slices.Contains(m, "OPTIONS")
+		if !slices.Contains(m, "GET") && !slices.Contains(m, "HEAD") {
+			item.NotAllowed = append(item.NotAllowed, "HEAD")
+			sort.Strings(item.NotAllowed)
+		}
+		paths = append(paths, item)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return paths[i].Path < paths[j].Path
+	})
+	return paths
+}
+
+// packageImports are the imports of the packages generated types refer to
+var packageImports = map[string]string{
+	"chi":      "github.com/go-chi/chi/v5",
+	"context":  "context",
+	"errors":   "errors",
+	"fmt":      "fmt",
+	"gohandlr": "github.com/epentland/gohandlr/pkg/gohandlr",
+	"http":     "net/http",
+	"json":     "encoding/json",
+	"time":     "time",
+}
+
+// qualifiedIdent matches the package of a qualified identifier in a Go type
+var qualifiedIdent = regexp.MustCompile(`\b(\w+)\.`)
+
+// StructImports returns the imports the types of the generated structs need, with
+// an empty string between the standard library and other packages
+func (o OpenAPIStructs) StructImports() []string {
+	var types []string
+	for _, endpoint := range o.EndpointList() {
+		for _, param := range endpoint.Params {
+			types = append(types, param.Type)
+		}
+	}
+	for _, component := range o.Components {
+		for _, field := range component.Fields {
+			types = append(types, field.Type)
+		}
+		if component.Additional != "" {
+			types = append(types, "map[string]"+component.Additional, "json.", "gohandlr.")
+		}
+		if len(component.TaglessFields()) > 0 {
+			types = append(types, "json.", "gohandlr.")
+		}
+	}
+
+	// The methods of enums and unions use fmt, json and gohandlr, except
+	// those of string enums, which only use fmt
+	for _, enum := range o.Enums {
+		types = append(types, "fmt.")
+		if enum.Type != "string" {
+			types = append(types, "json.", "gohandlr.")
+		}
+	}
+	for _, union := range o.Unions {
+		types = append(types, "fmt.", "json.", "gohandlr.")
+		for _, variant := range union.Variants {
+			types = append(types, variant.Type)
+		}
+	}
+	for _, endpoint := range o.EndpointList() {
+		for _, variant := range endpoint.Variants {
+			types = append(types, variant.Body, "gohandlr.", "http.Header")
+			for _, header := range variant.Headers {
+				types = append(types, header.Type)
+			}
+		}
+		for _, errorResponse := range endpoint.Errors {
+			types = append(types, errorResponse.Body, "gohandlr.")
+		}
+	}
+	return importsOf(types)
+}
+
+// ServerImports returns the imports of the Server interface and its
+// RegisterHandlers, with an empty string between the standard library and
+// other packages
+func (o OpenAPIStructs) ServerImports() []string {
+	types := []string{"context.", "errors.", "gohandlr.", "chi.Mux"}
+	if o.Router == RouterStdlib {
+		types[3] = "http.ServeMux"
+	}
+	for _, endpoint := range o.EndpointList() {
+		if endpoint.Response != nil {
+			types = append(types, endpoint.Response.Name)
+		}
+	}
+	return importsOf(types)
+}
+
+// ClientImports returns the imports of the client package besides the
+// generated package, with an empty string between the standard library and
+// other packages
+func (o OpenAPIStructs) ClientImports() []string {
+	types := []string{"context.", "gohandlr."}
+	for _, endpoint := range o.EndpointList() {
+		if endpoint.Response != nil {
+			types = append(types, endpoint.Response.Name)
+		}
+		for _, variant := range endpoint.Variants {
+			types = append(types, "http.Response")
+			if variant.Body != "" && variant.Body != fileType {
+				types = append(types, "json.")
+			}
+		}
+		if len(endpoint.Errors) > 0 {
+			types = append(types, "json.")
+		}
+	}
+	return importsOf(types)
+}
+
+// localType matches the types of the generated package in a Go type, which
+// aren't qualified by a package
+var localType = regexp.MustCompile(`(^|[^.\w])([A-Z]\w*)`)
+
+// qualify qualifies the types of the generated package in typ with the name
+// the client imports it as: []Pet becomes []api.Pet
+func qualify(typ string) string {
+	return localType.ReplaceAllString(typ, "${1}api.${2}")
+}
+
+// importsOf returns the imports of the packages the Go types refer to, with
+// an empty string between the standard library and other packages
+func importsOf(types []string) []string {
+	var std, other []string
+	for _, typ := range types {
+		for _, match := range qualifiedIdent.FindAllStringSubmatch(typ, -1) {
+			path, ok := packageImports[match[1]]
+			if !ok || slices.Contains(std, path) || slices.Contains(other, path) {
+				continue
+			}
+			if strings.Contains(path, ".") {
+				other = append(other, path)
+			} else {
+				std = append(std, path)
+			}
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+
+	// The standard library goes in its own group
+	if len(std) > 0 && len(other) > 0 {
+		std = append(std, "")
+	}
+	return append(std, other...)
+}
+
+// UsesDocs reports whether the spec is embedded in the package
+func (o OpenAPIStructs) UsesDocs() bool {
+	return o.Features.docs()
+}
+
+// UsesMethods reports whether paths answer OPTIONS, HEAD and 405 Method Not Allowed
+func (o OpenAPIStructs) UsesMethods() bool {
+	return o.Features.methods()
+}
+
+// UsesHead reports whether any path answers HEAD requests with its GET operation
+func (o OpenAPIStructs) UsesHead() bool {
+	if o.Router == RouterStdlib || !o.Features.methods() {
+		return false
+	}
+	for _, path := range o.Paths() {
+		if path.HasGet {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesRateLimit reports whether any endpoint is rate limited
+func (o OpenAPIStructs) UsesRateLimit() bool {
+	for _, endpoints := range o.Endpoints {
+		for _, endpoint := range endpoints {
+			if endpoint.RateLimit != nil {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func getTag(tags []string) string {
@@ -81,85 +486,135 @@ func getTag(tags []string) string {
 	}
 }
 
-func extractEndpointsAndComponents(doc *openapi3.T) OpenAPIStructs {
+func extractEndpointsAndComponents(doc *openapi3.T, order declarationOrder) OpenAPIStructs {
 	endpoints := make(map[string][]Endpoint, 0)
-	var components []Component
-	var hasRequest bool
+	// operationNames are the method and path of each operation name
+	operationNames := make(map[string]string)
 
-	for path, pathItem := range doc.Paths.Map() {
-		for method, operation := range pathItem.Operations() {
+	// Components are first so their types keep the names of the spec
+	types := newTypeBuilder(doc, order)
+	components := processComponents(doc, types)
+
+	for _, path := range order.Paths(doc.Paths) {
+		pathItem := doc.Paths.Value(path)
+		operations := pathItem.Operations()
+		for _, method := range order.Operations(path, operations) {
+			operation := operations[method]
+			operationId, err := operationName(method, path, operation)
+			if err != nil {
+				log.Fatalf("Error reading %s %s: %v", method, path, err)
+			}
+			if other, ok := operationNames[operationId]; ok {
+				log.Fatalf("Error reading %s %s: %s is also the name of %s, set an operationId or %s to tell them apart", method, path, operationId, other, goNameExtension)
+			}
+			operationNames[operationId] = method + " " + path
+			if types.names[operationId+"Input"] {
+				log.Fatalf("Error reading %s %s: %sInput is already the name of a schema", method, path, operationId)
+			}
+			types.names[operationId+"Input"] = true
+
+			// Operations with parameters or a body read an input struct,
+			// which has a Body next to the parameters
+			hasRequest := false
+			fieldNames := map[string]bool{"Body": true}
 			var params []Parameter
-			for _, param := range operation.Parameters {
+			for _, param := range operationParameters(pathItem, operation) {
+				goName := uniqueName(toCamel(param.Value.Name), fieldNames)
+				style, explode, err := paramStyle(param.Value)
+				if err != nil {
+					log.Fatalf("Error reading %s %s: %v", method, path, err)
+				}
+				defaultValue, err := paramDefault(param.Value)
+				if err != nil {
+					log.Fatalf("Error reading %s %s: %v", method, path, err)
+				}
 				params = append(params, Parameter{
-					Name: param.Value.Name,
-					Type: goType(param.Value.Schema),
-					Tag:  param.Value.In,
+					Name:      param.Value.Name,
+					Type:      paramType(types.goType(param.Value.Schema, operationId+goName), param.Value.Required),
+					Tag:       param.Value.In,
+					Required:  param.Value.Required,
+					Style:     style,
+					Explode:   explode,
+					Default:   defaultValue,
+					GoName:    goName,
+					StructTag: structTag("json", jsonTag(param.Value.Name, false), param.Value.In, param.Value.Name),
 				})
 				hasRequest = true
 			}
 
 			var requestBody *RequestBody
 			if operation.RequestBody != nil {
-				for contentType, content := range operation.RequestBody.Value.Content {
-					if contentType == "application/json" {
-						schemaRef := content.Schema
-						fields := make(map[string]string)
-						for fieldName, fieldSchema := range schemaRef.Value.Properties {
-							fields[fieldName] = goType(fieldSchema)
-						}
-
-						requestBody = &RequestBody{
-							Name:   cutPrefix(schemaRef.Ref),
-							Fields: fields,
-						}
-						hasRequest = true
+				if content := operation.RequestBody.Value.Content.Get("application/json"); content != nil {
+					schemaRef := content.Schema
+					requestBody = &RequestBody{
+						Name:   types.goType(schemaRef, operationId+"Body"),
+						Fields: types.fields(schemaRef, operationId+"Body"),
 					}
+					hasRequest = true
 				}
 			}
 
 			var responseBody *RequestBody
-			if len(operation.Responses.Map()) > 0 {
-				for _, response := range operation.Responses.Map() {
-					for contentType, content := range response.Value.Content {
-						if contentType == "application/json" {
-							schemaRef := content.Schema
-							fields := make(map[string]string)
-							for fieldName, fieldSchema := range schemaRef.Value.Properties {
-								fields[fieldName] = goType(fieldSchema)
-							}
-							name := cutPrefix(schemaRef.Ref)
-							if len(schemaRef.Value.Type.Slice()) > 0 && schemaRef.Value.Type.Slice()[0] == "array" {
-								name = "[]" + name
+			variants, err := responseVariants(operationId, operation.Responses, types)
+			if err != nil {
+				log.Fatalf("Error reading %s %s: %v", method, path, err)
+			}
+			if variants != nil {
+				// Operations with several success responses return one of
+				// the variants of their response type
+				responseBody = &RequestBody{Name: operationId + "Response"}
+			} else {
+				for _, status := range successStatuses(operation.Responses) {
+					response := operation.Responses.Value(status)
+					for _, contentType := range mapKeys(response.Value.Content) {
+						content := response.Value.Content[contentType]
+						if isBinarySchema(content.Schema) {
+							// Binary responses are streamed rather than marshaled
+							responseBody = &RequestBody{
+								Name: fileType,
 							}
+						} else if contentType == "application/json" {
+							schemaRef := content.Schema
 							responseBody = &RequestBody{
-								Name:   name,
-								Fields: fields,
+								Name:   types.goType(schemaRef, operationId+"Response"),
+								Fields: types.fields(schemaRef, operationId+"Response"),
 							}
 						}
 					}
+					// The first success response with a body is the response type
+					if responseBody != nil {
+						break
+					}
 				}
 			}
 
-			// Split the path into segments
-			segments := strings.Split(path, "/")
-
-			// Iterate over the segments and capitalize the first letter of each one
-			for i, segment := range segments {
-				if len(segment) > 0 {
-					segments[i] = strings.Title(segment)
+			var errorResponses []ErrorResponse
+			for _, status := range errorStatuses(operation.Responses) {
+				content := operation.Responses.Value(status).Value.Content.Get("application/json")
+				if content == nil {
+					continue
 				}
+				code, _ := strconv.Atoi(status)
+				statusRange := rangeDigit(status)
+				name := operationId + statusName(code)
+				if statusRange != 0 {
+					name = operationId + rangeName(statusRange)
+				}
+				if types.names[name] {
+					log.Fatalf("Error reading %s %s: %s is already the name of a schema", method, path, name)
+				}
+				types.names[name] = true
+				errorResponses = append(errorResponses, ErrorResponse{
+					Name:   name,
+					Status: code,
+					Range:  statusRange,
+					Body:   types.goType(content.Schema, name+"Body"),
+				})
 			}
 
-			// Join the segments back together
-			paths := strings.Join(segments, "")
-
-			operationId := strings.Title(strings.ToLower(method)) + paths
-
-			re := regexp.MustCompile(`\{(.*?)\}`)
-			operationId = re.ReplaceAllStringFunc(operationId, func(s string) string {
-				return strings.Trim(s, "{}")
-			})
-			// no request no response
+			// The state is the shape of the handler and process function:
+			// 0 without a request or response, 1 with a request, 2 with a
+			// response, and 3 with both
 			t := 0
 			if hasRequest {
 				t = 1
@@ -170,6 +625,11 @@ func extractEndpointsAndComponents(doc *openapi3.T) OpenAPIStructs {
 			if hasRequest && responseBody != nil {
 				t = 3
 			}
+			rateLimit, err := getRateLimit(operation)
+			if err != nil {
+				log.Fatalf("Error reading %s %s: %v", method, path, err)
+			}
+
 			tag := getTag(operation.Tags)
 			endpoints[tag] = append(endpoints[tag], Endpoint{
 				Path:        path,
@@ -178,60 +638,160 @@ func extractEndpointsAndComponents(doc *openapi3.T) OpenAPIStructs {
 				Params:      params,
 				Body:        requestBody,
 				Response:    responseBody,
+				Variants:    variants,
+				Errors:      errorResponses,
 				State:       t,
+				RateLimit:   rateLimit,
+				Security:    getSecurity(doc, operation),
 			})
 		}
 	}
 
-	componentMap := processComponents(doc)
+	// Writes to a resource with a GET check If-Match against its current
+	// tag. POST creates another resource, so it isn't checked.
+	getters := make(map[string]bool)
+	for _, list := range endpoints {
+		for _, endpoint := range list {
+			if endpoint.Method == http.MethodGet && endpoint.Response != nil {
+				getters[endpoint.Path] = true
+			}
+		}
+	}
+	for _, list := range endpoints {
+		for i, endpoint := range list {
+			switch endpoint.Method {
+			case http.MethodPut, http.MethodPatch, http.MethodDelete:
+				list[i].Current = getters[endpoint.Path]
+			}
+		}
+	}
 
-	for _, v := range componentMap {
-		components = append(components, v)
+	securitySchemes, err := processSecuritySchemes(doc)
+	if err != nil {
+		log.Fatalf("Error reading security schemes: %v", err)
 	}
 
 	return OpenAPIStructs{
-		Endpoints:  endpoints,
-		Components: components,
+		Endpoints:       endpoints,
+		Components:      append(components, types.structs...),
+		Enums:           types.enums,
+		Unions:          types.unions,
+		SecuritySchemes: securitySchemes,
+		Title:           doc.Info.Title,
+		Version:         doc.Info.Version,
 	}
 }
 
-func processComponents(doc *openapi3.T) map[string]Component {
-	componentMap := make(map[string]Component)
-	for componentName, componentSchema := range doc.Components.Schemas {
-		fields := make(map[string]string)
-		singularName := toCamel(componentName)
+// processComponents returns the component schemas in declaration order. An
+// array schema is followed by the plural struct holding its items, and enum,
+// oneOf and anyOf schemas are added to the named types of types.
+func processComponents(doc *openapi3.T, types *typeBuilder) []Component {
+	var components []Component
+	if doc.Components == nil {
+		return components
+	}
+	for _, componentName := range types.order.Schemas(doc.Components.Schemas) {
+		componentSchema := doc.Components.Schemas[componentName]
+		singularName := types.components[componentName]
 
-		pluralName := inflection.Plural(singularName)
+		pluralName := types.plurals[componentName]
 
 		t := componentSchema.Value.Type.Slice()
 
-		if len(t) > 0 && t[0] == "array" {
+		if len(componentSchema.Value.Enum) > 0 && isEnumType(baseType(componentSchema.Value)) {
+			types.defineEnum(componentSchema.Value, singularName)
+		} else if isUnion(componentSchema.Value) {
+			types.defineUnion(componentSchema.Value, singularName)
+		} else if len(t) > 0 && t[0] == "array" {
 			// Handle array schema
-			itemsSchema := componentSchema.Value.Items.Value
-			for fieldName, fieldSchema := range itemsSchema.Properties {
-				fields[fieldName] = goType(fieldSchema)
-			}
-			componentMap[singularName] = Component{
-				Name:   singularName,
-				Fields: fields,
-			}
+			components = append(components, types.component(componentSchema.Value.Items, singularName))
 
-			componentMap[pluralName] = Component{
+			components = append(components, Component{
 				Name: pluralName,
-				Fields: map[string]string{
-					singularName: "[]" + singularName,
+				Fields: []Field{
+					newField(singularName, singularName, "[]"+singularName, false),
 				},
-			}
+			})
 		} else {
 			// Handle object schema
-			for fieldName, fieldSchema := range componentSchema.Value.Properties {
-				fields[fieldName] = goType(fieldSchema)
-			}
-			componentMap[singularName] = Component{
-				Name:   singularName,
-				Fields: fields,
-			}
+			components = append(components, types.component(componentSchema, singularName))
 		}
 	}
-	return componentMap
+	return components
+}
+
+// successStatuses returns the 2XX statuses of responses in order. Without
+// any, the default response is the success.
+func successStatuses(responses *openapi3.Responses) []string {
+	var statuses []string
+	for _, status := range mapKeys(responses.Map()) {
+		if strings.HasPrefix(status, "2") {
+			statuses = append(statuses, status)
+		}
+	}
+	if len(statuses) == 0 && responses.Default() != nil {
+		statuses = append(statuses, "default")
+	}
+	return statuses
+}
+
+// errorStatuses returns the 4XX and 5XX status codes of responses in order,
+// followed by the 4XX and 5XX ranges, and by default when it isn't the
+// success. The specific codes come first, as the client matches a status in
+// that order.
+func errorStatuses(responses *openapi3.Responses) []string {
+	var statuses, ranges []string
+	for _, status := range mapKeys(responses.Map()) {
+		if code, err := strconv.Atoi(status); err == nil && code >= 400 && code <= 599 {
+			statuses = append(statuses, status)
+		} else if rangeDigit(status) != 0 {
+			ranges = append(ranges, status)
+		}
+	}
+	statuses = append(statuses, ranges...)
+	if responses.Default() != nil && !slices.Contains(successStatuses(responses), "default") {
+		statuses = append(statuses, "default")
+	}
+	return statuses
+}
+
+// statusName is the name of a status code in the name of its error type,
+// like NotFound for 404 and Default for 0
+func statusName(code int) string {
+	if code == 0 {
+		return "Default"
+	}
+	if text := http.StatusText(code); text != "" {
+		return toCamel(text)
+	}
+	return "Status" + strconv.Itoa(code)
+}
+
+// rangeDigit returns 4 for the 4XX range and 5 for 5XX, and 0 for anything else
+func rangeDigit(status string) int {
+	switch strings.ToUpper(status) {
+	case "4XX":
+		return 4
+	case "5XX":
+		return 5
+	}
+	return 0
+}
+
+// rangeName is the name of a range in the name of its error type, like
+// ClientError for 4XX
+func rangeName(digit int) string {
+	if digit == 4 {
+		return "ClientError"
+	}
+	return "ServerError"
+}
+
+// EndpointList returns every endpoint, ordered by tag and then by declaration
+func (o OpenAPIStructs) EndpointList() []Endpoint {
+	var list []Endpoint
+	for _, tag := range mapKeys(o.Endpoints) {
+		list = append(list, o.Endpoints[tag]...)
+	}
+	return list
 }
@@ -0,0 +1,318 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// processImports are the imports of the packages the code added to
+// process.go refers to
+var processImports = map[string]string{
+	"chi":      "github.com/go-chi/chi/v5",
+	"context":  "context",
+	"gohandlr": "github.com/epentland/gohandlr/pkg/gohandlr",
+	"http":     "net/http",
+}
+
+// mergeProcess adds what the spec needs to the process.go written by the
+// user: a process function for each new operation, the current function of
+// those that check If-Match, and the registration of its handler in
+// RegisterHandlers. Code that is already there is never
+// changed; process functions whose signature no longer matches their
+// operation, and those of operations that were removed, are reported in the
+// warnings instead.
+func mergeProcess(src []byte, data OpenAPIStructs, tmpl *template.Template, router string) ([]byte, []string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "process.go", src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing process.go: %w", err)
+	}
+
+	funcs := make(map[string]*ast.FuncDecl)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+			funcs[fn.Name.Name] = fn
+		}
+	}
+
+	var edits []textEdit
+	var warnings []string
+	var added bytes.Buffer
+	operations := make(map[string]bool)
+	for _, endpoint := range data.EndpointList() {
+		stubs := [][2]string{{"process" + endpoint.OperationID, "ProcessEndpoint"}}
+		if endpoint.Current {
+			stubs = append(stubs, [2]string{"current" + endpoint.OperationID, "CurrentEndpoint"})
+		}
+		for _, s := range stubs {
+			name := s[0]
+			operations[name] = true
+
+			var stub bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&stub, s[1], endpoint); err != nil {
+				return nil, nil, fmt.Errorf("executing template: %w", err)
+			}
+			fn, ok := funcs[name]
+			if !ok {
+				added.WriteString("\n" + strings.TrimSpace(stub.String()) + "\n")
+				continue
+			}
+			expected, err := stubSignature(stub.Bytes())
+			if err != nil {
+				return nil, nil, err
+			}
+			if actual := signature(fn.Type); actual != expected {
+				warnings = append(warnings, fmt.Sprintf("%s is %s, but %s %s now needs %s", name, actual, strings.ToUpper(endpoint.Method), endpoint.Path, expected))
+			}
+		}
+	}
+	for _, name := range mapKeys(funcs) {
+		if !operations[name] && generatedShape(name, funcs[name].Type) {
+			warnings = append(warnings, fmt.Sprintf("%s has no operation in the spec anymore, remove it if it isn't used", name))
+		}
+	}
+
+	register, ok := funcs["RegisterHandlers"]
+	if ok {
+		edits = append(edits, registerEdits(fset, register, data, router)...)
+	} else {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, "RegisterHandlers", data); err != nil {
+			return nil, nil, fmt.Errorf("executing template: %w", err)
+		}
+		added.WriteString("\n" + strings.TrimSpace(buf.String()) + "\n")
+	}
+	edits = append(edits, textEdit{offset: len(src), text: added.String()})
+
+	var insertedText strings.Builder
+	for _, edit := range edits {
+		insertedText.WriteString(edit.text)
+	}
+	merged := applyEdits(src, edits)
+	merged, err = addImports(merged, usedImports(insertedText.String()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	formatted, err := format.Source(merged)
+	if err != nil {
+		return nil, nil, fmt.Errorf("formatting process.go: %w", err)
+	}
+	return formatted, warnings, nil
+}
+
+// registerEdits adds the calls RegisterHandlers is missing: registerPaths
+// at the start, and the registration of each handler at the end
+func registerEdits(fset *token.FileSet, register *ast.FuncDecl, data OpenAPIStructs, router string) []textEdit {
+	called := make(map[string]bool)
+	ast.Inspect(register.Body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok {
+				called[ident.Name] = true
+			}
+		}
+		return true
+	})
+
+	// Handlers get the options of RegisterHandlers, if it takes any
+	args := ""
+	if params := register.Type.Params.List; len(params) > 1 && len(params[len(params)-1].Names) > 0 {
+		if _, ok := params[len(params)-1].Type.(*ast.Ellipsis); ok {
+			args = params[len(params)-1].Names[0].Name + "..."
+		}
+	}
+	mux := "r"
+	if params := register.Type.Params.List; len(params) > 0 && len(params[0].Names) > 0 {
+		mux = params[0].Names[0].Name
+	}
+
+	var edits []textEdit
+	if !called["registerPaths"] {
+		call := fmt.Sprintf("registerPaths(%s)", joinArgs(mux, args))
+		edits = append(edits, textEdit{offset: fset.Position(register.Body.Lbrace).Offset + 1, text: "\n\t" + call + "\n"})
+	}
+	var calls strings.Builder
+	for _, endpoint := range data.EndpointList() {
+		handler := "Handle" + endpoint.OperationID
+		if called[handler] {
+			continue
+		}
+		call := registerHandler(router, fmt.Sprintf("%s(%s)", handler, args))
+		// The calls registerHandler makes are on r
+		call = mux + strings.TrimPrefix(call, "r")
+		calls.WriteString("\t" + call + "\n")
+	}
+	if calls.Len() > 0 {
+		edits = append(edits, textEdit{offset: fset.Position(register.Body.Rbrace).Offset, text: "\n" + calls.String()})
+	}
+	return edits
+}
+
+// joinArgs joins the arguments of a call that aren't empty
+func joinArgs(args ...string) string {
+	var nonEmpty []string
+	for _, arg := range args {
+		if arg != "" {
+			nonEmpty = append(nonEmpty, arg)
+		}
+	}
+	return strings.Join(nonEmpty, ", ")
+}
+
+// signature is the type of a function, without the names of its parameters,
+// like func(context.Context, GetPetInput) (Pet, error)
+func signature(fn *ast.FuncType) string {
+	fields := func(list *ast.FieldList) []string {
+		if list == nil {
+			return nil
+		}
+		var exprs []string
+		for _, field := range list.List {
+			n := len(field.Names)
+			if n == 0 {
+				n = 1
+			}
+			for i := 0; i < n; i++ {
+				exprs = append(exprs, types.ExprString(field.Type))
+			}
+		}
+		return exprs
+	}
+	result := "func(" + strings.Join(fields(fn.Params), ", ") + ")"
+	switch results := fields(fn.Results); len(results) {
+	case 0:
+	case 1:
+		result += " " + results[0]
+	default:
+		result += " (" + strings.Join(results, ", ") + ")"
+	}
+	return result
+}
+
+// generatedShape reports whether a function looks like one mergeProcess
+// added for an operation: a process or current function named after it that
+// takes a context and the input struct of the operation, if any, and returns
+// an error last. Other functions, like a helper named processPagination, are
+// the user's own.
+func generatedShape(name string, fn *ast.FuncType) bool {
+	operation, current := strings.CutPrefix(name, "current")
+	if !current {
+		var ok bool
+		if operation, ok = strings.CutPrefix(name, "process"); !ok {
+			return false
+		}
+	}
+	if operation == "" || !token.IsExported(operation) {
+		return false
+	}
+
+	params, results, _ := strings.Cut(signature(fn), ") ")
+	switch strings.TrimPrefix(params, "func(") {
+	case "context.Context", "context.Context, " + operation + "Input":
+	default:
+		return false
+	}
+	if current {
+		return results == "(string, error)"
+	}
+	return results == "error" || (strings.HasPrefix(results, "(") && strings.HasSuffix(results, ", error)"))
+}
+
+// stubSignature is the signature of the function declared by a stub
+func stubSignature(stub []byte) (string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", append([]byte("package p\n"), stub...), 0)
+	if err != nil {
+		return "", fmt.Errorf("parsing process function: %w", err)
+	}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			return signature(fn.Type), nil
+		}
+	}
+	return "", fmt.Errorf("process function not found in %s", stub)
+}
+
+// textEdit inserts text at an offset of a file
+type textEdit struct {
+	offset int
+	text   string
+}
+
+// applyEdits inserts the text of edits into src, keeping the order of edits
+// at the same offset
+func applyEdits(src []byte, edits []textEdit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].offset < edits[j].offset
+	})
+	var buf bytes.Buffer
+	last := 0
+	for _, edit := range edits {
+		buf.Write(src[last:edit.offset])
+		buf.WriteString(edit.text)
+		last = edit.offset
+	}
+	buf.Write(src[last:])
+	return buf.Bytes()
+}
+
+// usedImports returns the import paths of the packages code refers to
+func usedImports(code string) []string {
+	var paths []string
+	for _, match := range qualifiedIdent.FindAllStringSubmatch(code, -1) {
+		if path, ok := processImports[match[1]]; ok && !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// addImports adds the import paths that src doesn't import yet
+func addImports(src []byte, paths []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "process.go", src, parser.ImportsOnly)
+	if err != nil {
+		return nil, fmt.Errorf("parsing process.go: %w", err)
+	}
+	imported := make(map[string]bool)
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		imported[path] = true
+	}
+	var std, other strings.Builder
+	for _, path := range paths {
+		switch {
+		case imported[path]:
+		case isStdImport(strconv.Quote(path)):
+			std.WriteString("\n\t" + strconv.Quote(path))
+		default:
+			other.WriteString("\t" + strconv.Quote(path) + "\n")
+		}
+	}
+	if std.Len() == 0 && other.Len() == 0 {
+		return src, nil
+	}
+
+	// The standard library goes at the start of the first import block and
+	// the rest at its end, which keeps the groups of goimports. Without an
+	// import block, one is added after the package clause.
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT && gen.Lparen.IsValid() {
+			return applyEdits(src, []textEdit{
+				{offset: fset.Position(gen.Lparen).Offset + 1, text: std.String()},
+				{offset: fset.Position(gen.Rparen).Offset, text: other.String()},
+			}), nil
+		}
+	}
+	return applyEdits(src, []textEdit{{offset: fset.Position(file.Name.End()).Offset, text: "\n\nimport (" + std.String() + "\n" + other.String() + ")\n"}}), nil
+}
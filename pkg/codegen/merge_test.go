@@ -0,0 +1,151 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+)
+
+// mergeEndpoints are the operations of the spec process.go is merged with
+var mergeEndpoints = map[string][]Endpoint{
+	"default": {
+		{Method: "DELETE", Path: "/session", OperationID: "Logout", State: 0},
+		{Method: "GET", Path: "/health", OperationID: "GetHealth", State: 2, Response: &RequestBody{Name: "Health"}},
+		{Method: "GET", Path: "/logo", OperationID: "GetLogo", State: 2, Response: &RequestBody{Name: fileType}},
+		{Method: "GET", Path: "/items/{id}", OperationID: "GetItem", State: 3, Response: &RequestBody{Name: "Item"}},
+		{Method: "DELETE", Path: "/items/{id}", OperationID: "DeleteItem", State: 1, Params: []Parameter{{Name: "id"}}, Current: true},
+	},
+}
+
+const userProcess = `package api
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RegisterHandlers wires the API. It used to call HandleGetHealth too.
+func RegisterHandlers(
+	r *chi.Mux,
+	options ...gohandlr.Option,
+) {
+	registerPaths(r, options...)
+	r.MethodFunc(HandleLogout(options...))
+	r.MethodFunc(HandleGetItem(append(options, gohandlr.WithETag())...))
+}
+
+// processLogout ends the session
+func processLogout(ctx context.Context) error {
+	// Keep this comment and the code around it
+	return sessions.End(ctx)
+}
+
+func processGetItem(ctx context.Context, req GetItemInput) (OldItem, error) {
+	return OldItem{}, nil
+}
+
+func processListItems(ctx context.Context) ([]Item, error) {
+	return nil, nil
+}
+
+func currentRemoveItem(ctx context.Context, req RemoveItemInput) (string, error) {
+	return "", nil
+}
+
+// Helpers that aren't shaped like a process function aren't warned about
+func processPagination(items []Item, limit int) []Item {
+	return items
+}
+
+func processRows(ctx context.Context, rows Rows) error {
+	return nil
+}
+
+func helper() {}
+`
+
+func TestMergeProcess(t *testing.T) {
+	tmpl := parseTemplates(Target{Router: RouterChi})
+	merged, warnings, err := mergeProcess([]byte(userProcess), OpenAPIStructs{Endpoints: mergeEndpoints}, tmpl, RouterChi)
+	if err != nil {
+		t.Fatalf("Failed to merge: %v", err)
+	}
+	content := string(merged)
+
+	for _, expected := range []string{
+		// The code of the user is kept
+		"// RegisterHandlers wires the API. It used to call HandleGetHealth too.",
+		"\t// Keep this comment and the code around it\n\treturn sessions.End(ctx)",
+		"func processGetItem(ctx context.Context, req GetItemInput) (OldItem, error) {",
+		"func processListItems(ctx context.Context) ([]Item, error) {",
+		"r.MethodFunc(HandleGetItem(append(options, gohandlr.WithETag())...))",
+		// New operations are added
+		"func processGetHealth(ctx context.Context) (Health, error) {",
+		"func processGetLogo(ctx context.Context) (gohandlr.File, error) {",
+		"\tr.MethodFunc(HandleGetHealth(options...))\n\tr.MethodFunc(HandleGetLogo(options...))\n\tr.MethodFunc(HandleDeleteItem(options...))\n}",
+		// Writes that check If-Match get a current function after their process function
+		"func processDeleteItem(ctx context.Context, req DeleteItemInput) error {\n\treturn nil\n}\n\n// currentDeleteItem",
+		"func currentDeleteItem(ctx context.Context, req DeleteItemInput) (string, error) {",
+		`"github.com/epentland/gohandlr/pkg/gohandlr"`,
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected the merged process.go to contain %q, got:\n%s", expected, content)
+		}
+	}
+	for _, registered := range []string{"HandleLogout(", "HandleGetItem(", "registerPaths("} {
+		if n := strings.Count(content, registered); n != 1 {
+			t.Errorf("Expected %s to be called once, got: %d", registered, n)
+		}
+	}
+
+	expectedWarnings := []string{
+		"processGetItem is func(context.Context, GetItemInput) (OldItem, error), but GET /items/{id} now needs func(context.Context, GetItemInput) (Item, error)",
+		"currentRemoveItem has no operation in the spec anymore, remove it if it isn't used",
+		"processListItems has no operation in the spec anymore, remove it if it isn't used",
+	}
+	if strings.Join(warnings, "\n") != strings.Join(expectedWarnings, "\n") {
+		t.Errorf("Expected warnings: %q, got: %q", expectedWarnings, warnings)
+	}
+
+	// Merging again adds nothing
+	again, _, err := mergeProcess(merged, OpenAPIStructs{Endpoints: mergeEndpoints}, tmpl, RouterChi)
+	if err != nil {
+		t.Fatalf("Failed to merge: %v", err)
+	}
+	if string(again) != content {
+		t.Errorf("Expected a second merge to change nothing, got:\n%s", again)
+	}
+}
+
+func TestMergeProcessRegisterHandlers(t *testing.T) {
+	tmpl := parseTemplates(Target{Router: RouterStdlib})
+
+	// Without options, handlers are registered without them
+	src := "package api\n\nimport \"net/http\"\n\nfunc RegisterHandlers(mux *http.ServeMux) {\n}\n"
+	merged, _, err := mergeProcess([]byte(src), OpenAPIStructs{Endpoints: mergeEndpoints}, tmpl, RouterStdlib)
+	if err != nil {
+		t.Fatalf("Failed to merge: %v", err)
+	}
+	for _, expected := range []string{
+		"\tregisterPaths(mux)\n",
+		"\tmux.HandleFunc(gohandlr.Pattern(HandleLogout()))\n",
+		"\"context\"",
+	} {
+		if !strings.Contains(string(merged), expected) {
+			t.Errorf("Expected the merged process.go to contain %q, got:\n%s", expected, merged)
+		}
+	}
+
+	// A missing RegisterHandlers is added
+	merged, _, err = mergeProcess([]byte("package api\n"), OpenAPIStructs{Endpoints: mergeEndpoints}, tmpl, RouterStdlib)
+	if err != nil {
+		t.Fatalf("Failed to merge: %v", err)
+	}
+	if !strings.Contains(string(merged), "func RegisterHandlers(r *http.ServeMux, options ...gohandlr.Option) {") {
+		t.Errorf("Expected RegisterHandlers to be added, got:\n%s", merged)
+	}
+
+	if _, _, err := mergeProcess([]byte("package api\n\nfunc broken( {\n"), OpenAPIStructs{Endpoints: mergeEndpoints}, tmpl, RouterStdlib); err == nil {
+		t.Errorf("Expected a process.go that doesn't parse to fail")
+	}
+}
@@ -0,0 +1,111 @@
+package codegen
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+	"unicode"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// goNameExtension overrides the Go name of an operation
+const goNameExtension = "x-go-name"
+
+// commonInitialisms are written in upper case in Go names, like ID in GetUserByID
+var commonInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true,
+	"EOF": true, "GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "JWT": true, "LHS": true, "OK": true, "QPS": true,
+	"RAM": true, "RHS": true, "RPC": true, "SKU": true, "SLA": true, "SMTP": true,
+	"SQL": true, "SSH": true, "TCP": true, "TLS": true, "TTL": true, "UDP": true,
+	"UI": true, "UID": true, "URI": true, "URL": true, "UTF8": true, "UUID": true,
+	"VM": true, "XML": true, "XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// operationName is the Go name of an operation: its x-go-name, its
+// operationId following the Go rules for initialisms, or, without either,
+// the method and path, like PutUsersId for PUT /users/{id}
+func operationName(method, path string, operation *openapi3.Operation) (string, error) {
+	if value, ok := operation.Extensions[goNameExtension]; ok {
+		name, ok := value.(string)
+		if !ok || !token.IsIdentifier(name) || !token.IsExported(name) {
+			return "", fmt.Errorf("invalid %s: %v is not an exported Go identifier", goNameExtension, value)
+		}
+		return name, nil
+	}
+	if operation.OperationID != "" {
+		return goName(operation.OperationID), nil
+	}
+
+	name := titleWords(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		name += titleWords(strings.Trim(segment, "{}"))
+	}
+	return name, nil
+}
+
+// goName turns a name like get-user_by_id or getUserById into an exported Go
+// name, GetUserByID
+func goName(s string) string {
+	var name strings.Builder
+	for _, word := range splitWords(s) {
+		if upper := strings.ToUpper(word); commonInitialisms[upper] {
+			name.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		name.WriteString(string(runes))
+	}
+	return exported(name.String())
+}
+
+// splitWords splits a name into words at characters other than letters and
+// digits, and where the case changes, so getHTTPServer is get, HTTP, Server
+func splitWords(s string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			continue
+		}
+		if len(word) > 0 && unicode.IsUpper(r) {
+			prev := word[len(word)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				words = append(words, string(word))
+				word = nil
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// titleWords upper cases the first letter of each word of s and leaves out
+// what separates them, other than underscores
+func titleWords(s string) string {
+	var result strings.Builder
+	capNext := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			capNext = true
+			continue
+		}
+		if capNext {
+			r = unicode.ToUpper(r)
+			capNext = false
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
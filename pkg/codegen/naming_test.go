@@ -0,0 +1,41 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestOperationName(t *testing.T) {
+	tests := []struct {
+		method    string
+		path      string
+		operation *openapi3.Operation
+		expected  string
+	}{
+		{"PUT", "/users/{id}", &openapi3.Operation{}, "PutUsersId"},
+		{"GET", "/pet-photos/{petId}", &openapi3.Operation{}, "GetPetPhotosPetId"},
+		{"GET", "/users/{id}", &openapi3.Operation{OperationID: "getUserById"}, "GetUserByID"},
+		{"GET", "/users", &openapi3.Operation{OperationID: "list-users_by_url"}, "ListUsersByURL"},
+		{"GET", "/", &openapi3.Operation{OperationID: "getHTTPServerStatus"}, "GetHTTPServerStatus"},
+		{"GET", "/", &openapi3.Operation{OperationID: "uploadJsonApi"}, "UploadJSONAPI"},
+		{"GET", "/", &openapi3.Operation{OperationID: "getUser", Extensions: map[string]any{"x-go-name": "FetchUser"}}, "FetchUser"},
+	}
+	for _, test := range tests {
+		name, err := operationName(test.method, test.path, test.operation)
+		if err != nil {
+			t.Errorf("Failed to name %s %s: %v", test.method, test.path, err)
+			continue
+		}
+		if name != test.expected {
+			t.Errorf("Expected name: %s, got: %s", test.expected, name)
+		}
+	}
+
+	for _, invalid := range []any{"fetchUser", "Fetch User", 1} {
+		operation := &openapi3.Operation{Extensions: map[string]any{"x-go-name": invalid}}
+		if _, err := operationName("GET", "/", operation); err == nil {
+			t.Errorf("Expected x-go-name %v to be invalid", invalid)
+		}
+	}
+}
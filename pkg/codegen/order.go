@@ -0,0 +1,271 @@
+package codegen
+
+import (
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// declarationOrder is the order keys are declared in the spec, which the Go
+// maps of openapi3 lose. Keys it doesn't know, like those from other files,
+// are sorted, so the generated code is the same on every run.
+type declarationOrder struct {
+	paths []string
+	// operations are the methods of each path
+	operations map[string][]string
+	schemas    []string
+	// properties are the property names of the schemas in the spec, by the
+	// JSON pointer of the schema, like /components/schemas/Item
+	properties map[string][]string
+	// locations are the JSON pointers of the schemas of the loaded doc
+	locations map[*openapi3.Schema]string
+}
+
+// readDeclarationOrder reads the order of a JSON or YAML spec, loaded as
+// doc. A spec it can't read gets sorted keys everywhere.
+func readDeclarationOrder(specPath string, doc *openapi3.T) declarationOrder {
+	order := declarationOrder{
+		operations: make(map[string][]string),
+		properties: make(map[string][]string),
+		locations:  make(map[*openapi3.Schema]string),
+	}
+	order.locate(doc)
+
+	content, err := os.ReadFile(specPath)
+	if err != nil {
+		return order
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil || len(node.Content) == 0 {
+		return order
+	}
+	root := node.Content[0]
+
+	if paths := mappingValue(root, "paths"); paths != nil {
+		order.paths = mappingKeys(paths)
+		for i := 1; i < len(paths.Content); i += 2 {
+			path := paths.Content[i-1].Value
+			for _, key := range mappingKeys(paths.Content[i]) {
+				order.operations[path] = append(order.operations[path], strings.ToUpper(key))
+			}
+		}
+	}
+	if components := mappingValue(root, "components"); components != nil {
+		order.schemas = mappingKeys(mappingValue(components, "schemas"))
+	}
+	order.readProperties(root, "")
+	return order
+}
+
+// readProperties records the property names of every mapping with
+// properties under node, which is at pointer
+func (o declarationOrder) readProperties(node *yaml.Node, pointer string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		if properties := mappingValue(node, "properties"); properties != nil && properties.Kind == yaml.MappingNode {
+			o.properties[pointer] = mappingKeys(properties)
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			o.readProperties(node.Content[i], pointerTo(pointer, node.Content[i-1].Value))
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			o.readProperties(child, pointerTo(pointer, strconv.Itoa(i)))
+		}
+	}
+}
+
+// locate records the JSON pointer of each schema declared in doc. A
+// referenced schema is located where it is declared, not where it is used.
+func (o declarationOrder) locate(doc *openapi3.T) {
+	if doc == nil {
+		return
+	}
+	if components := doc.Components; components != nil {
+		for name, schema := range components.Schemas {
+			o.locateSchema(schema, pointerTo("/components/schemas", name))
+		}
+		for name, parameter := range components.Parameters {
+			if parameter != nil && parameter.Ref == "" {
+				o.locateParameter(parameter.Value, pointerTo("/components/parameters", name))
+			}
+		}
+		for name, header := range components.Headers {
+			if header != nil && header.Ref == "" && header.Value != nil {
+				o.locateParameter(&header.Value.Parameter, pointerTo("/components/headers", name))
+			}
+		}
+		for name, body := range components.RequestBodies {
+			if body != nil && body.Ref == "" && body.Value != nil {
+				o.locateContent(body.Value.Content, pointerTo("/components/requestBodies", name)+"/content")
+			}
+		}
+		for name, response := range components.Responses {
+			o.locateResponse(response, pointerTo("/components/responses", name))
+		}
+	}
+	if doc.Paths == nil {
+		return
+	}
+	for path, item := range doc.Paths.Map() {
+		pointer := pointerTo("/paths", path)
+		o.locateParameters(item.Parameters, pointer+"/parameters")
+		for method, operation := range item.Operations() {
+			pointer := pointerTo(pointer, strings.ToLower(method))
+			o.locateParameters(operation.Parameters, pointer+"/parameters")
+			if body := operation.RequestBody; body != nil && body.Ref == "" && body.Value != nil {
+				o.locateContent(body.Value.Content, pointer+"/requestBody/content")
+			}
+			if operation.Responses != nil {
+				for status, response := range operation.Responses.Map() {
+					o.locateResponse(response, pointerTo(pointer+"/responses", status))
+				}
+			}
+		}
+	}
+}
+
+func (o declarationOrder) locateParameters(parameters openapi3.Parameters, pointer string) {
+	for i, parameter := range parameters {
+		if parameter != nil && parameter.Ref == "" {
+			o.locateParameter(parameter.Value, pointerTo(pointer, strconv.Itoa(i)))
+		}
+	}
+}
+
+func (o declarationOrder) locateParameter(parameter *openapi3.Parameter, pointer string) {
+	if parameter == nil {
+		return
+	}
+	o.locateSchema(parameter.Schema, pointer+"/schema")
+	o.locateContent(parameter.Content, pointer+"/content")
+}
+
+func (o declarationOrder) locateResponse(response *openapi3.ResponseRef, pointer string) {
+	if response == nil || response.Ref != "" || response.Value == nil {
+		return
+	}
+	o.locateContent(response.Value.Content, pointer+"/content")
+	for name, header := range response.Value.Headers {
+		if header != nil && header.Ref == "" && header.Value != nil {
+			o.locateParameter(&header.Value.Parameter, pointerTo(pointer+"/headers", name))
+		}
+	}
+}
+
+func (o declarationOrder) locateContent(content openapi3.Content, pointer string) {
+	for mediaType, media := range content {
+		if media != nil {
+			o.locateSchema(media.Schema, pointerTo(pointer, mediaType)+"/schema")
+		}
+	}
+}
+
+func (o declarationOrder) locateSchema(ref *openapi3.SchemaRef, pointer string) {
+	if ref == nil || ref.Ref != "" || ref.Value == nil {
+		return
+	}
+	s := ref.Value
+	if _, ok := o.locations[s]; ok {
+		return
+	}
+	o.locations[s] = pointer
+	for name, property := range s.Properties {
+		o.locateSchema(property, pointerTo(pointer+"/properties", name))
+	}
+	o.locateSchema(s.Items, pointer+"/items")
+	o.locateSchema(s.Not, pointer+"/not")
+	o.locateSchema(s.AdditionalProperties.Schema, pointer+"/additionalProperties")
+	for keyword, members := range map[string]openapi3.SchemaRefs{"allOf": s.AllOf, "anyOf": s.AnyOf, "oneOf": s.OneOf} {
+		for i, member := range members {
+			o.locateSchema(member, pointer+"/"+keyword+"/"+strconv.Itoa(i))
+		}
+	}
+}
+
+// pointerTo appends a key to a JSON pointer, escaping ~ and /
+func pointerTo(pointer, key string) string {
+	return pointer + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+// Paths returns the paths of the spec in declaration order
+func (o declarationOrder) Paths(paths *openapi3.Paths) []string {
+	return inOrder(mapKeys(paths.Map()), o.paths)
+}
+
+// Operations returns the methods of a path in declaration order
+func (o declarationOrder) Operations(path string, operations map[string]*openapi3.Operation) []string {
+	return inOrder(mapKeys(operations), o.operations[path])
+}
+
+// Schemas returns the names of the component schemas in declaration order
+func (o declarationOrder) Schemas(schemas openapi3.Schemas) []string {
+	return inOrder(mapKeys(schemas), o.schemas)
+}
+
+// Properties returns the property names of a schema in declaration order
+func (o declarationOrder) Properties(s *openapi3.Schema) []string {
+	var declared []string
+	if pointer, ok := o.locations[s]; ok {
+		declared = o.properties[pointer]
+	}
+	return inOrder(mapKeys(s.Properties), declared)
+}
+
+// inOrder returns keys in the order they are declared, followed by the keys
+// that aren't declared, sorted
+func inOrder(keys, declared []string) []string {
+	ordered := make([]string, 0, len(keys))
+	for _, key := range declared {
+		if slices.Contains(keys, key) && !slices.Contains(ordered, key) {
+			ordered = append(ordered, key)
+		}
+	}
+	var rest []string
+	for _, key := range keys {
+		if !slices.Contains(ordered, key) {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(ordered, rest...)
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mappingValue returns the value of key in a YAML mapping, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 1; i < len(node.Content); i += 2 {
+		if node.Content[i-1].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+// mappingKeys returns the keys of a YAML mapping in order
+func mappingKeys(node *yaml.Node) []string {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	keys := make([]string, 0, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	return keys
+}
@@ -0,0 +1,55 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// operationParameters returns the parameters of the path followed by those
+// of the operation, which override the path's with the same name and location
+func operationParameters(pathItem *openapi3.PathItem, operation *openapi3.Operation) openapi3.Parameters {
+	var params openapi3.Parameters
+	for _, param := range pathItem.Parameters {
+		if operation.Parameters.GetByInAndName(param.Value.In, param.Value.Name) == nil {
+			params = append(params, param)
+		}
+	}
+	return append(params, operation.Parameters...)
+}
+
+// paramStyle returns how the value of a parameter is serialized, which
+// defaults to form for query parameters and cookies and to simple for path
+// parameters and headers
+func paramStyle(param *openapi3.Parameter) (string, bool, error) {
+	method, err := param.SerializationMethod()
+	if err != nil {
+		return "", false, fmt.Errorf("parameter %s: %w", param.Name, err)
+	}
+	return method.Style, method.Explode, nil
+}
+
+// paramDefault returns the JSON of the default value of a parameter, or an
+// empty string without one
+func paramDefault(param *openapi3.Parameter) (string, error) {
+	if param.Schema == nil || param.Schema.Value == nil || param.Schema.Value.Default == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(param.Schema.Value.Default)
+	if err != nil {
+		return "", fmt.Errorf("default of parameter %s: %w", param.Name, err)
+	}
+	return string(data), nil
+}
+
+// paramType returns the Go type of the field of a parameter. Optional
+// parameters are pointers, so that a zero value like limit=0 is told apart
+// from a missing one, unless their type can already be nil.
+func paramType(goType string, required bool) string {
+	if required || goType == anyType || strings.HasPrefix(goType, "*") || strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "map[") {
+		return goType
+	}
+	return "*" + goType
+}
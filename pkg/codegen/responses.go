@@ -0,0 +1,129 @@
+package codegen
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ResponseVariant is a success response of an operation with several,
+// generated as a variant of the response type of the operation
+type ResponseVariant struct {
+	Name string
+	// Status is the status code of the response
+	Status int
+	// MediaType is the media type of the body, empty without one
+	MediaType string
+	// Body is the Go type of the body, empty without one
+	Body    string
+	Headers []ResponseHeader
+}
+
+// ResponseHeader is a header documented by a response variant, set from a
+// field of the variant
+type ResponseHeader struct {
+	Name string
+	// GoName is the field of the header in the variant
+	GoName string
+	Type   string
+}
+
+// ResponseHeaders returns the names of the headers the variants of an
+// endpoint document, sorted, which idempotent replays repeat
+func (e Endpoint) ResponseHeaders() []string {
+	var names []string
+	for _, variant := range e.Variants {
+		for _, header := range variant.Headers {
+			if !slices.Contains(names, header.Name) {
+				names = append(names, header.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// responseVariants returns the variants of the response type of an
+// operation with several 2XX and 3XX responses: one for each media type of
+// a status with a JSON or binary body, or one without a body for a status
+// without either. Operations with a single one return none, and keep the
+// type of its body as their response.
+func responseVariants(operationId string, responses *openapi3.Responses, types *typeBuilder) ([]ResponseVariant, error) {
+	var variants []ResponseVariant
+	for _, status := range variantStatuses(responses) {
+		response := responses.Value(status).Value
+		code, _ := strconv.Atoi(status)
+		var mediaTypes []string
+		for _, mediaType := range mapKeys(response.Content) {
+			if mediaType == "application/json" || isBinarySchema(response.Content[mediaType].Schema) {
+				mediaTypes = append(mediaTypes, mediaType)
+			}
+		}
+		if len(mediaTypes) == 0 {
+			variants = append(variants, ResponseVariant{Name: operationId + statusName(code), Status: code})
+		}
+		for _, mediaType := range mediaTypes {
+			name := operationId + statusName(code)
+			if len(mediaTypes) > 1 {
+				// The status has a variant for each media type
+				name += goName(mediaType[strings.Index(mediaType, "/")+1:])
+			}
+			variants = append(variants, ResponseVariant{Name: name, Status: code, MediaType: mediaType})
+		}
+	}
+	if len(variants) < 2 {
+		return nil, nil
+	}
+
+	if types.names[operationId+"Response"] {
+		return nil, fmt.Errorf("%sResponse is already the name of a schema", operationId)
+	}
+	types.names[operationId+"Response"] = true
+	for i, variant := range variants {
+		if types.names[variant.Name] {
+			return nil, fmt.Errorf("%s is already the name of a schema", variant.Name)
+		}
+		types.names[variant.Name] = true
+
+		response := responses.Value(strconv.Itoa(variant.Status)).Value
+		if variant.MediaType != "" {
+			schema := response.Content[variant.MediaType].Schema
+			if isBinarySchema(schema) {
+				variants[i].Body = fileType
+			} else {
+				variants[i].Body = types.goType(schema, variant.Name+"Body")
+			}
+		}
+
+		fieldNames := map[string]bool{"Body": true}
+		for _, name := range mapKeys(response.Headers) {
+			// The Content-Type is the one of the body
+			if strings.EqualFold(name, "Content-Type") {
+				continue
+			}
+			header := ResponseHeader{Name: name, GoName: uniqueName(toCamel(name), fieldNames), Type: "string"}
+			if schema := response.Headers[name].Value.Schema; schema != nil {
+				header.Type = types.goType(schema, variant.Name+header.GoName)
+			}
+			variants[i].Headers = append(variants[i].Headers, header)
+		}
+	}
+	return variants, nil
+}
+
+// variantStatuses returns the 2XX and 3XX status codes of responses in
+// order. Ranges like 2XX aren't a status code of their own, so they are left
+// out.
+func variantStatuses(responses *openapi3.Responses) []string {
+	var statuses []string
+	for _, status := range mapKeys(responses.Map()) {
+		if code, err := strconv.Atoi(status); err == nil && code >= 200 && code <= 399 {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
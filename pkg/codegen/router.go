@@ -0,0 +1,45 @@
+package codegen
+
+import (
+	"fmt"
+	"text/template"
+)
+
+// routerFuncs are the template functions for the code that depends on the router of a target
+func routerFuncs(router string) template.FuncMap {
+	return template.FuncMap{
+		// RouterType is the type of the router RegisterHandlers takes
+		"RouterType": func() string {
+			if router == RouterStdlib {
+				return "*http.ServeMux"
+			}
+			return "*chi.Mux"
+		},
+		// Route starts a call registering a handler for method and path
+		"Route": func(method, path string) string {
+			if router == RouterStdlib {
+				return fmt.Sprintf("r.HandleFunc(%q, ", method+" "+path)
+			}
+			return fmt.Sprintf("r.MethodFunc(%q, %q, ", method, path)
+		},
+		// RegisterHandler registers the method, path and handler returned by call
+		"RegisterHandler": func(call string) string {
+			return registerHandler(router, call)
+		},
+		// PathParam reads a path parameter of the request r
+		"PathParam": func(name string) string {
+			if router == RouterStdlib {
+				return fmt.Sprintf("r.PathValue(%q)", name)
+			}
+			return fmt.Sprintf("chi.URLParam(r, %q)", name)
+		},
+	}
+}
+
+// registerHandler registers the method, path and handler returned by a Handle function call
+func registerHandler(router, call string) string {
+	if router == RouterStdlib {
+		return fmt.Sprintf("r.HandleFunc(gohandlr.Pattern(%s))", call)
+	}
+	return fmt.Sprintf("r.MethodFunc(%s)", call)
+}
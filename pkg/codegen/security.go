@@ -0,0 +1,96 @@
+package codegen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// SecurityScheme is a security scheme from components.securitySchemes
+type SecurityScheme struct {
+	// Name is the name of the scheme in the spec
+	Name string
+	// Method is the Authenticator method that implements the scheme
+	Method string
+	// Credentials are the parameters of Method for the credentials of the scheme
+	Credentials string
+	// Scheme is the gohandlr.Scheme expression that calls Method
+	Scheme      string
+	Description string
+}
+
+// processSecuritySchemes reads the security schemes of the spec, sorted by name
+func processSecuritySchemes(doc *openapi3.T) ([]SecurityScheme, error) {
+	if doc.Components == nil {
+		return nil, nil
+	}
+
+	var schemes []SecurityScheme
+	methods := make(map[string]bool)
+	for _, name := range mapKeys(doc.Components.SecuritySchemes) {
+		ref := doc.Components.SecuritySchemes[name]
+		if ref == nil || ref.Value == nil {
+			continue
+		}
+		s := ref.Value
+		scheme := SecurityScheme{
+			Name:   name,
+			Method: uniqueName("Authenticate"+toCamel(name), methods),
+		}
+		method := "auth." + scheme.Method
+
+		switch {
+		case s.Type == "http" && strings.EqualFold(s.Scheme, "bearer"):
+			scheme.Credentials = "token string"
+			scheme.Scheme = fmt.Sprintf("gohandlr.BearerScheme(%s)", method)
+			scheme.Description = "the bearer token of the " + name + " scheme"
+		case s.Type == "http" && strings.EqualFold(s.Scheme, "basic"):
+			scheme.Credentials = "username, password string"
+			scheme.Scheme = fmt.Sprintf("gohandlr.BasicScheme(%s)", method)
+			scheme.Description = "the username and password of the " + name + " scheme"
+		case s.Type == "apiKey":
+			scheme.Credentials = "key string"
+			scheme.Scheme = fmt.Sprintf("gohandlr.APIKeyScheme(%q, %q, %s)", s.In, s.Name, method)
+			scheme.Description = fmt.Sprintf("the API key in the %s %s of the %s scheme", s.Name, s.In, name)
+		case s.Type == "oauth2" || s.Type == "openIdConnect":
+			scheme.Credentials = "token string"
+			scheme.Scheme = fmt.Sprintf("gohandlr.BearerScheme(%s)", method)
+			scheme.Description = "the access token and scopes of the " + name + " scheme"
+		default:
+			return nil, fmt.Errorf("security scheme %s: unsupported type %s %s", name, s.Type, s.Scheme)
+		}
+		schemes = append(schemes, scheme)
+	}
+	return schemes, nil
+}
+
+// getSecurity returns the gohandlr.SecurityRequirement expressions of an
+// operation. Operations without their own security use the top-level one.
+func getSecurity(doc *openapi3.T, operation *openapi3.Operation) []string {
+	requirements := doc.Security
+	if operation.Security != nil {
+		requirements = *operation.Security
+	}
+
+	var security []string
+	for _, requirement := range requirements {
+		names := make([]string, 0, len(requirement))
+		for name := range requirement {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		entries := make([]string, 0, len(names))
+		for _, name := range names {
+			scopes := make([]string, 0, len(requirement[name]))
+			for _, scope := range requirement[name] {
+				scopes = append(scopes, fmt.Sprintf("%q", scope))
+			}
+			entries = append(entries, fmt.Sprintf("%q: {%s}", name, strings.Join(scopes, ", ")))
+		}
+		security = append(security, "gohandlr.SecurityRequirement{"+strings.Join(entries, ", ")+"}")
+	}
+	return security
+}
@@ -0,0 +1,313 @@
+package codegen
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/jinzhu/inflection"
+)
+
+// anyType holds values whose schema doesn't say what they are
+const anyType = "json.RawMessage"
+
+// typeBuilder finds the Go types of schemas and collects the named types
+// that inline schemas need, like enums
+type typeBuilder struct {
+	order  declarationOrder
+	enums  []Enum
+	unions []Union
+	// structs are the inline objects, named after where they are
+	structs []Component
+	// names are the Go type names that are taken
+	names map[string]bool
+	// components are the Go names of the component schemas, and plurals
+	// those of the structs holding the items of array schemas
+	components map[string]string
+	plurals    map[string]string
+	// named are the types generated for inline schemas, so a schema reached
+	// through several references is generated once
+	named map[*openapi3.Schema]string
+}
+
+func newTypeBuilder(doc *openapi3.T, order declarationOrder) *typeBuilder {
+	b := &typeBuilder{
+		order:      order,
+		names:      make(map[string]bool),
+		named:      make(map[*openapi3.Schema]string),
+		components: make(map[string]string),
+		plurals:    make(map[string]string),
+	}
+	for _, name := range packageNames {
+		b.names[name] = true
+	}
+	if doc.Components != nil {
+		for _, name := range order.Schemas(doc.Components.Schemas) {
+			b.components[name] = b.reserve(toCamel(name))
+			if schema := doc.Components.Schemas[name]; schema.Value != nil && schemaType(schema.Value) == "array" {
+				b.plurals[name] = b.reserve(inflection.Plural(b.components[name]))
+			}
+		}
+	}
+	return b
+}
+
+// packageNames are declared by the generated package besides its types
+var packageNames = []string{"Authenticator", "OpenAPI", "RegisterHandlers", "Server", "UnimplementedServer", "Version", "WithAuthenticator"}
+
+// refName is the Go name of the component schema a reference is to
+func (b *typeBuilder) refName(ref string) string {
+	if name, ok := b.components[cutPrefix(ref)]; ok {
+		return name
+	}
+	return toCamel(cutPrefix(ref))
+}
+
+// reserve takes a name for a type, adding a number when it's taken
+func (b *typeBuilder) reserve(name string) string {
+	return uniqueName(name, b.names)
+}
+
+// goType is the Go type of a schema. Referenced objects are the structs
+// generated for them, and enums get a named type, called name when the
+// schema is inline.
+func (b *typeBuilder) goType(schema *openapi3.SchemaRef, name string) string {
+	if schema == nil || schema.Value == nil {
+		return anyType
+	}
+	s := schema.Value
+
+	if len(s.Enum) > 0 && isEnumType(baseType(s)) {
+		if schema.Ref != "" {
+			return b.refName(schema.Ref)
+		}
+		return b.enum(s, name)
+	}
+	if isUnion(s) {
+		if schema.Ref != "" {
+			return b.refName(schema.Ref)
+		}
+		return b.union(s, name)
+	}
+	// allOf with a single schema, used to add nullable or a description to
+	// a reference, is that schema
+	if schema.Ref == "" && len(s.AllOf) == 1 && len(s.Properties) == 0 {
+		return b.goType(s.AllOf[0], name)
+	}
+
+	switch schemaType(s) {
+	case "array":
+		// The struct of an array schema is the struct of its items
+		if schema.Ref != "" {
+			return "[]" + b.refName(schema.Ref)
+		}
+		if s.Items != nil {
+			return "[]" + b.goType(s.Items, itemName(name))
+		}
+		return "[]" + anyType
+	case "object":
+		if schema.Ref != "" {
+			return b.refName(schema.Ref)
+		}
+		if len(b.properties(s)) > 0 {
+			return b.object(schema, name)
+		}
+		if typ := b.additionalType(s, name); typ != "" {
+			return "map[string]" + typ
+		}
+		return "map[string]" + anyType
+	default:
+		return baseType(s)
+	}
+}
+
+// object returns the struct of an inline object, defining it the first time
+func (b *typeBuilder) object(schema *openapi3.SchemaRef, name string) string {
+	if named, ok := b.named[schema.Value]; ok {
+		return named
+	}
+	name = b.reserve(name)
+	b.named[schema.Value] = name
+
+	// The struct is added before those of its properties, so it is declared first
+	index := len(b.structs)
+	b.structs = append(b.structs, Component{})
+	b.structs[index] = b.component(schema, name)
+	return name
+}
+
+// component returns the struct of an object schema called name
+func (b *typeBuilder) component(schema *openapi3.SchemaRef, name string) Component {
+	component := Component{
+		Name:   name,
+		Fields: b.fields(schema, name),
+	}
+	if schema != nil && schema.Value != nil {
+		component.Additional = b.additionalType(schema.Value, name)
+	}
+	return component
+}
+
+// additionalType is the Go type of the additionalProperties of an object,
+// or empty when it has none
+func (b *typeBuilder) additionalType(s *openapi3.Schema, name string) string {
+	if s.AdditionalProperties.Schema != nil {
+		return b.goType(s.AdditionalProperties.Schema, name+"Value")
+	}
+	if s.AdditionalProperties.Has != nil && *s.AdditionalProperties.Has {
+		return anyType
+	}
+	return ""
+}
+
+// baseType is the Go type of a schema that isn't an array or object
+func baseType(s *openapi3.Schema) string {
+	switch schemaType(s) {
+	case "string":
+		return stringType(s.Format)
+	case "integer":
+		switch s.Format {
+		case "int32":
+			return "int32"
+		case "int64":
+			return "int64"
+		}
+		return "int"
+	case "number":
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	default:
+		return anyType
+	}
+}
+
+// stringType is the Go type of a string with a format
+func stringType(format string) string {
+	switch format {
+	case "date-time":
+		return "time.Time"
+	case "date":
+		return "gohandlr.Date"
+	case "uuid":
+		return "gohandlr.UUID"
+	case "byte", "binary":
+		// encoding/json reads and writes []byte as base64
+		return "[]byte"
+	default:
+		return "string"
+	}
+}
+
+// itemName is the name of the items of an array called name, like PetTag for
+// PetTags
+func itemName(name string) string {
+	singular := inflection.Singular(name)
+	if singular == name {
+		return name + "Item"
+	}
+	return singular
+}
+
+// schemaType is the type of a schema besides null. Schemas without a type
+// that have properties are objects, and allOf has the type of its schemas.
+func schemaType(s *openapi3.Schema) string {
+	for _, t := range s.Type.Slice() {
+		if t != "null" {
+			return t
+		}
+	}
+	if len(s.Properties) > 0 || s.AdditionalProperties.Schema != nil {
+		return "object"
+	}
+	for _, member := range s.AllOf {
+		if member.Value != nil {
+			if t := schemaType(member.Value); t != "" {
+				return t
+			}
+		}
+	}
+	return ""
+}
+
+// isNullable reports whether a schema allows null
+func isNullable(s *openapi3.Schema) bool {
+	return s.Nullable || s.Type.Includes("null")
+}
+
+// fieldType is the Go type of a property. Properties that can be left out
+// or be null are pointers, unless their type can already be nil.
+func (b *typeBuilder) fieldType(schema *openapi3.SchemaRef, name string, required bool) (typ string, omitEmpty bool) {
+	typ = b.goType(schema, name)
+	nullable := schema == nil || schema.Value == nil || isNullable(schema.Value)
+	if required && !nullable {
+		return typ, false
+	}
+	if !canBeNil(typ) {
+		typ = "*" + typ
+	}
+	return typ, !required
+}
+
+// fields returns the properties of a schema in declaration order, with
+// those of the schemas of allOf first. Inline types of the properties are
+// named after parent.
+func (b *typeBuilder) fields(schema *openapi3.SchemaRef, parent string) []Field {
+	if schema == nil || schema.Value == nil {
+		return nil
+	}
+	required := requiredProperties(schema.Value)
+	// Structs with additionalProperties keep them in AdditionalProperties
+	taken := map[string]bool{"AdditionalProperties": true}
+	var fields []Field
+	for _, property := range b.properties(schema.Value) {
+		goName := uniqueName(toCamel(property.name), taken)
+		typ, omitEmpty := b.fieldType(property.schema, parent+goName, slices.Contains(required, property.name))
+		fields = append(fields, newField(property.name, goName, typ, omitEmpty))
+	}
+	return fields
+}
+
+type property struct {
+	name   string
+	schema *openapi3.SchemaRef
+}
+
+// properties flattens the properties of a schema and the schemas of its
+// allOf. A property declared again replaces the earlier one in place.
+func (b *typeBuilder) properties(s *openapi3.Schema) []property {
+	var properties []property
+	add := func(name string, schema *openapi3.SchemaRef) {
+		i := slices.IndexFunc(properties, func(p property) bool { return p.name == name })
+		if i < 0 {
+			properties = append(properties, property{name, schema})
+		} else {
+			properties[i].schema = schema
+		}
+	}
+	for _, member := range s.AllOf {
+		if member.Value == nil {
+			continue
+		}
+		for _, p := range b.properties(member.Value) {
+			add(p.name, p.schema)
+		}
+	}
+	for _, name := range b.order.Properties(s) {
+		add(name, s.Properties[name])
+	}
+	return properties
+}
+
+// newField returns the field of a property, tagged with its name
+func newField(name, goName, typ string, omitEmpty bool) Field {
+	return Field{Name: name, GoName: goName, Type: typ, OmitEmpty: omitEmpty, StructTag: structTag("json", jsonTag(name, omitEmpty))}
+}
+
+// canBeNil reports whether the zero value of a Go type is nil
+func canBeNil(typ string) bool {
+	return strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || strings.HasPrefix(typ, "*") || typ == anyType
+}
@@ -0,0 +1,152 @@
+package codegen
+
+import (
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Union is a named type for a oneOf or anyOf schema, holding one of its variants
+type Union struct {
+	Name string
+	// OneOf requires JSON to match exactly one variant, while anyOf takes
+	// the first that matches
+	OneOf    bool
+	Variants []UnionVariant
+	// Discriminator is the property naming the variant of the JSON, if any
+	Discriminator string
+}
+
+// UnionVariant is one of the types a Union can hold
+type UnionVariant struct {
+	// Name is used by the accessor and constructor, like AsCat and PetFromCat
+	Name string
+	Type string
+	// Required are the properties JSON needs to match the variant
+	Required []string
+	// Values are the discriminator values of the variant
+	Values []string
+}
+
+// isUnion reports whether a schema is a oneOf or anyOf
+func isUnion(s *openapi3.Schema) bool {
+	return len(s.OneOf) > 0 || len(s.AnyOf) > 0
+}
+
+// union returns the named type of an inline union, defining it the first time
+func (b *typeBuilder) union(s *openapi3.Schema, name string) string {
+	if named, ok := b.named[s]; ok {
+		return named
+	}
+	name = b.reserve(name)
+	b.defineUnion(s, name)
+	return name
+}
+
+// defineUnion adds the type of a oneOf or anyOf schema called name
+func (b *typeBuilder) defineUnion(s *openapi3.Schema, name string) {
+	b.named[s] = name
+	union := Union{Name: name, OneOf: len(s.OneOf) > 0}
+	members := s.OneOf
+	if !union.OneOf {
+		members = s.AnyOf
+	}
+
+	// The union is added before its variants are, so it is declared first
+	index := len(b.unions)
+	b.unions = append(b.unions, union)
+
+	// variants are the indexes of the variants of the members
+	variants := make(map[int]int)
+	var names []string
+	for i, member := range members {
+		// null is allowed by the pointer of nullable unions
+		if member.Value != nil && member.Value.Type.Is("null") {
+			continue
+		}
+		typ := b.goType(member, name+"Variant"+strconv.Itoa(i+1))
+		variant := UnionVariant{
+			Name: variantName(typ),
+			Type: typ,
+		}
+		if variant.Name == "" {
+			variant.Name = "Variant" + strconv.Itoa(i+1)
+		}
+		for slices.Contains(names, variant.Name) {
+			variant.Name += strconv.Itoa(i + 1)
+		}
+		names = append(names, variant.Name)
+		variants[i] = len(union.Variants)
+		if member.Value != nil {
+			variant.Required = requiredProperties(member.Value)
+		}
+		union.Variants = append(union.Variants, variant)
+	}
+
+	if s.Discriminator != nil {
+		union.Discriminator = s.Discriminator.PropertyName
+		mapped := make(map[string]bool)
+		for _, value := range mapKeys(s.Discriminator.Mapping) {
+			ref := s.Discriminator.Mapping[value]
+			i, ok := variants[slices.IndexFunc(members, func(member *openapi3.SchemaRef) bool {
+				return member.Ref == ref
+			})]
+			if !ok {
+				log.Fatalf("Error reading %s: discriminator value %q maps to %s, which isn't one of its schemas", name, value, ref)
+			}
+			union.Variants[i].Values = append(union.Variants[i].Values, value)
+			mapped[value] = true
+		}
+		// Variants are also named by their schema
+		for i, member := range members {
+			if value := cutPrefix(member.Ref); member.Ref != "" && !mapped[value] {
+				union.Variants[variants[i]].Values = append(union.Variants[variants[i]].Values, value)
+			}
+		}
+		for _, variant := range union.Variants {
+			if len(variant.Values) == 0 {
+				log.Fatalf("Error reading %s: %s has no discriminator value", name, variant.Type)
+			}
+		}
+	}
+
+	b.unions[index] = union
+}
+
+// variantName is the name of a variant of a Go type, like Cat for Cat or
+// StringList for []string
+func variantName(typ string) string {
+	var suffix string
+	for strings.HasPrefix(typ, "[]") {
+		typ = strings.TrimPrefix(typ, "[]")
+		suffix += "List"
+	}
+	if value, ok := strings.CutPrefix(typ, "map[string]"); ok {
+		typ = value
+		suffix = "Map" + suffix
+	}
+	if _, name, ok := strings.Cut(typ, "."); ok {
+		typ = name
+	}
+	return identifierWords(typ) + suffix
+}
+
+// requiredProperties returns the required properties of a schema and the
+// schemas it is made of with allOf
+func requiredProperties(s *openapi3.Schema) []string {
+	required := slices.Clone(s.Required)
+	for _, member := range s.AllOf {
+		if member.Value == nil {
+			continue
+		}
+		for _, name := range requiredProperties(member.Value) {
+			if !slices.Contains(required, name) {
+				required = append(required, name)
+			}
+		}
+	}
+	return required
+}
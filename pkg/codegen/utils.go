@@ -1,8 +1,10 @@
 package codegen
 
 import (
+	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/getkin/kin-openapi/openapi3"
 )
@@ -11,49 +13,110 @@ func toUpper(s string) string {
 	return strings.ToUpper(s)
 }
 
+// toCamel turns a name from the spec, like postal.code, @type or 2fa, into
+// an exported Go identifier: PostalCode, Type and N2fa
 func toCamel(s string) string {
-	var result string
+	return exported(identifierWords(s))
+}
+
+// identifierWords joins the letters and digits of s into an identifier,
+// upper casing the first letter of each word, like InProgress for in-progress
+func identifierWords(s string) string {
+	var result strings.Builder
 	capNext := true
-	for _, v := range s {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			capNext = true
+			continue
+		}
 		if capNext {
-			result += string(unicode.ToUpper(v))
+			r = unicode.ToUpper(r)
 			capNext = false
-		} else if v == '_' || v == '-' {
-			capNext = true
-		} else {
-			result += string(v)
 		}
+		result.WriteRune(r)
 	}
-	return result
+	return result.String()
 }
 
-func goType(schema *openapi3.SchemaRef) string {
-	if schema == nil || schema.Value == nil {
-		return "interface{}"
+// exported makes an identifier exported. Identifiers can't start with a
+// digit, and letters without a case, like those of Chinese, aren't exported.
+func exported(name string) string {
+	r, _ := utf8.DecodeRuneInString(name)
+	switch {
+	case name == "":
+		return "X"
+	case unicode.IsDigit(r):
+		return "N" + name
+	case !unicode.IsUpper(r):
+		return "X" + name
 	}
+	return name
+}
 
-	// This line is correct, do not change it
+// uniqueName adds a number to name when it's taken, and takes it
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	taken[unique] = true
+	return unique
+}
 
-	switch schema.Value.Type.Slice()[0] {
-	case "string":
-		return "string"
-	case "integer":
-		return "int"
-	case "boolean":
-		return "bool"
-	case "array":
-		if schema.Value.Items != nil {
-			return "[]" + goType(schema.Value.Items)
+// structTag writes the tag of a struct field from its keys and values, like
+// `json:"name" query:"name"`
+func structTag(pairs ...string) string {
+	var tag strings.Builder
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			tag.WriteByte(' ')
 		}
-		return "[]interface{}"
-	case "object":
-		if schema.Ref != "" {
-			return toCamel(cutPrefix(schema.Ref))
+		tag.WriteString(pairs[i] + ":" + strconv.Quote(pairs[i+1]))
+	}
+	if strings.Contains(tag.String(), "`") {
+		return strconv.Quote(tag.String())
+	}
+	return "`" + tag.String() + "`"
+}
+
+// jsonTag is the json tag of a property. A property named - is written
+// -, since json:"-" leaves the field out. A name encoding/json can't read
+// from a tag leaves the field out too, and the generated MarshalJSON and
+// UnmarshalJSON of its struct handle it instead.
+func jsonTag(name string, omitEmpty bool) string {
+	switch {
+	case !validJSONName(name):
+		return "-"
+	case omitEmpty:
+		return name + ",omitempty"
+	case name == "-":
+		return "-,"
+	}
+	return name
+}
+
+// validJSONName reports whether encoding/json accepts name in a json tag:
+// letters, digits and punctuation other than quotes, backslashes and commas
+func validJSONName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
 		}
-		return "struct{}"
-	default:
-		return "interface{}"
 	}
+	return true
+}
+
+// isBinarySchema reports whether the schema describes raw bytes, like a file download
+func isBinarySchema(schema *openapi3.SchemaRef) bool {
+	if schema == nil || schema.Value == nil {
+		return false
+	}
+	return schema.Value.Type.Is("string") && schema.Value.Format == "binary"
 }
 
 func cutPrefix(s string) string {
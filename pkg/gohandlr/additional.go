@@ -0,0 +1,138 @@
+package gohandlr
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+// MarshalAdditional marshals the struct v and adds the additional properties
+// of an object to it. Additional properties named like one of the declared
+// properties are left out.
+func MarshalAdditional[T any](v any, additional map[string]T, properties ...string) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(additional) == 0 {
+		return data, err
+	}
+
+	keys := make([]string, 0, len(additional))
+	for key := range additional {
+		if !slices.Contains(properties, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for _, key := range keys {
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(additional[key])
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalAdditional reads the properties of a JSON object that aren't one
+// of the declared properties into additional, which is nil when there are none
+func UnmarshalAdditional[T any](data []byte, additional *map[string]T, properties ...string) error {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+
+	*additional = nil
+	for key, raw := range object {
+		if slices.Contains(properties, key) {
+			continue
+		}
+		var value T
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		if *additional == nil {
+			*additional = make(map[string]T)
+		}
+		(*additional)[key] = value
+	}
+	return nil
+}
+
+// Property is a property of a JSON object whose name can't be a json tag,
+// like a"b. Value points to the field that holds it.
+type Property struct {
+	Name      string
+	Value     any
+	OmitEmpty bool
+}
+
+// MarshalProperties adds properties to the JSON object in data
+func MarshalProperties(data []byte, properties ...Property) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for _, p := range properties {
+		if p.OmitEmpty && isEmptyValue(reflect.ValueOf(p.Value).Elem()) {
+			continue
+		}
+		name, err := json.Marshal(p.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(p.Value)
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalProperties reads properties from the JSON object in data. A
+// property the object doesn't have leaves its field as it is.
+func UnmarshalProperties(data []byte, properties ...Property) error {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	for _, p := range properties {
+		raw, ok := object[p.Name]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, p.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isEmptyValue reports whether omitempty leaves v out, as encoding/json does
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Struct:
+		return false
+	}
+	return v.IsZero()
+}
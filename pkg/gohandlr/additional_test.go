@@ -0,0 +1,87 @@
+package gohandlr
+
+import (
+	"testing"
+)
+
+func TestMarshalAdditional(t *testing.T) {
+	type settings struct {
+		Theme string `json:"theme"`
+	}
+
+	data, err := MarshalAdditional(settings{Theme: "dark"}, map[string]int{"volume": 7, "brightness": 3, "theme": 1}, "theme")
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	expected := `{"theme":"dark","brightness":3,"volume":7}`
+	if string(data) != expected {
+		t.Errorf("Expected JSON: %s, got: %s", expected, data)
+	}
+
+	data, err = MarshalAdditional(struct{}{}, map[string]int{"volume": 7})
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if string(data) != `{"volume":7}` {
+		t.Errorf("Expected JSON: %s, got: %s", `{"volume":7}`, data)
+	}
+}
+
+func TestUnmarshalAdditional(t *testing.T) {
+	var additional map[string]int
+	if err := UnmarshalAdditional([]byte(`{"theme": "dark", "volume": 7}`), &additional, "theme"); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if len(additional) != 1 || additional["volume"] != 7 {
+		t.Errorf("Expected additional properties: %v, got: %v", map[string]int{"volume": 7}, additional)
+	}
+
+	if err := UnmarshalAdditional([]byte(`{"theme": "dark"}`), &additional, "theme"); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if additional != nil {
+		t.Errorf("Expected no additional properties, got: %v", additional)
+	}
+
+	if err := UnmarshalAdditional([]byte(`{"volume": "loud"}`), &additional); err == nil {
+		t.Errorf("Expected an invalid additional property to fail")
+	}
+}
+
+func TestMarshalProperties(t *testing.T) {
+	quote, empty := "q", ""
+	data, err := MarshalProperties([]byte(`{"theme":"dark"}`),
+		Property{Name: `a"b`, Value: &quote, OmitEmpty: true},
+		Property{Name: "a`b", Value: &empty, OmitEmpty: true},
+		Property{Name: "it's", Value: &empty},
+	)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	expected := `{"theme":"dark","a\"b":"q","it's":""}`
+	if string(data) != expected {
+		t.Errorf("Expected JSON: %s, got: %s", expected, data)
+	}
+
+	data, err = MarshalProperties([]byte(`{}`), Property{Name: `a"b`, Value: &quote})
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if string(data) != `{"a\"b":"q"}` {
+		t.Errorf("Expected JSON: %s, got: %s", `{"a\"b":"q"}`, data)
+	}
+}
+
+func TestUnmarshalProperties(t *testing.T) {
+	quote, backtick := "", "kept"
+	if err := UnmarshalProperties([]byte(`{"a\"b": "q", "theme": "dark"}`), Property{Name: `a"b`, Value: &quote}, Property{Name: "a`b", Value: &backtick}); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if quote != "q" || backtick != "kept" {
+		t.Errorf("Expected properties: %q and %q, got: %q and %q", "q", "kept", quote, backtick)
+	}
+
+	if err := UnmarshalProperties([]byte(`{"a\"b": 1}`), Property{Name: `a"b`, Value: &quote}); err == nil {
+		t.Errorf("Expected an invalid property to fail")
+	}
+}
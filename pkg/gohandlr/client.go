@@ -0,0 +1,246 @@
+package gohandlr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client sends the requests of a generated client. Parameters are written
+// with the same styles ReadParam and ReadPathParam read them, and bodies
+// are sent as JSON.
+type Client struct {
+	// BaseURL is prepended to the path of each operation, like
+	// https://api.example.com/v1
+	BaseURL    string
+	HTTPClient *http.Client
+	// RequestEditors are applied to every request before it is sent
+	RequestEditors []RequestEditor
+}
+
+// RequestEditor changes a request before it is sent, to add a token for
+// instance
+type RequestEditor func(ctx context.Context, req *http.Request) error
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithHTTPClient sends the requests with client instead of
+// http.DefaultClient
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		c.HTTPClient = client
+	}
+}
+
+// WithRequestEditor applies editor to every request
+func WithRequestEditor(editor RequestEditor) ClientOption {
+	return func(c *Client) {
+		c.RequestEditors = append(c.RequestEditors, editor)
+	}
+}
+
+// NewClient creates a Client that sends requests to baseURL
+func NewClient(baseURL string, options ...ClientOption) *Client {
+	c := &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Request is an operation to send
+type Request struct {
+	Method string
+	// Path is the path of the operation, with its path parameters in
+	// braces like /pets/{id}
+	Path   string
+	Params []ParamValue
+	// Body is marshaled as JSON when it isn't nil
+	Body any
+	// DecodeError decodes a response that isn't 2xx into the error type the
+	// spec documents for its status. It returns nil for statuses without
+	// one, and for bodies that don't decode, which are returned as a
+	// *ResponseError.
+	DecodeError func(status int, body []byte) error
+}
+
+// ParamValue is the value of a parameter of a Request
+type ParamValue struct {
+	Param
+	Value any
+}
+
+// ResponseDecoder decodes a response whose type depends on its status. As
+// the resp of Do, it gets every response below 400, 3XX included, so that
+// they don't become a *ResponseError.
+type ResponseDecoder func(res *http.Response, body []byte) error
+
+// ResponseError is returned by Do for responses that aren't 2xx
+type ResponseError struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *ResponseError) Error() string {
+	message := fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if body := strings.TrimSpace(string(e.Body)); body != "" {
+		message += ": " + body
+	}
+	return message
+}
+
+// Status returns the status code of the response, so that the error is
+// passed on with it when a handler returns it
+func (e *ResponseError) Status() int {
+	return e.StatusCode
+}
+
+// Do sends req and decodes the response into resp, unless it is nil. A
+// *File resp gets the content and headers of the response as they are, a
+// ResponseDecoder decodes it itself, and anything else is unmarshaled from
+// JSON. Responses that aren't 2xx are decoded by req.DecodeError, or else
+// returned as a *ResponseError.
+func (c *Client) Do(ctx context.Context, req Request, resp any, editors ...RequestEditor) error {
+	r, err := c.newRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, editor := range append(append([]RequestEditor{}, c.RequestEditors...), editors...) {
+		if err := editor(ctx, r); err != nil {
+			return err
+		}
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	if decode, ok := resp.(ResponseDecoder); ok && res.StatusCode < 400 {
+		return decode(res, body)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		if req.DecodeError != nil {
+			if err := req.DecodeError(res.StatusCode, body); err != nil {
+				return err
+			}
+		}
+		return &ResponseError{StatusCode: res.StatusCode, Header: res.Header, Body: body}
+	}
+	if resp == nil {
+		return nil
+	}
+	if file, ok := resp.(*File); ok {
+		*file = ResponseFile(res, body)
+		return nil
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, resp); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// newRequest builds the http.Request of req
+func (c *Client) newRequest(ctx context.Context, req Request) (*http.Request, error) {
+	path := req.Path
+	query := url.Values{}
+	header := http.Header{}
+	var cookies []*http.Cookie
+	for _, param := range req.Params {
+		values, err := encodeParam(param.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s parameter %s: %w", param.In, param.Name, err)
+		}
+		if !values.present {
+			if param.Required {
+				return nil, fmt.Errorf("missing %s parameter %s", param.In, param.Name)
+			}
+			continue
+		}
+		switch param.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+param.Name+"}", param.pathValue(values))
+		case "query":
+			param.writeQuery(query, values)
+		case "header":
+			header.Add(param.Name, param.headerValue(values))
+		case "cookie":
+			cookies = append(cookies, &http.Cookie{Name: param.Name, Value: param.headerValue(values)})
+		}
+	}
+
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if req.Body != nil {
+		content, err := json.Marshal(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("encoding request body: %w", err)
+		}
+		body = bytes.NewReader(content)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, req.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	return r, nil
+}
+
+// ResponseFile creates a File from the content and headers of a response
+func ResponseFile(res *http.Response, body []byte) File {
+	file := File{
+		Content:     bytes.NewReader(body),
+		ContentType: res.Header.Get("Content-Type"),
+		ETag:        res.Header.Get("ETag"),
+	}
+	if disposition, params, err := mime.ParseMediaType(res.Header.Get("Content-Disposition")); err == nil {
+		file.Name = params["filename"]
+		file.Inline = disposition == "inline"
+	}
+	if modTime, err := http.ParseTime(res.Header.Get("Last-Modified")); err == nil {
+		file.ModTime = modTime
+	}
+	return file
+}
+
+// Ptr returns a pointer to v, to set the optional parameters of an input
+// like ListPetsInput{Limit: gohandlr.Ptr(0)}
+func Ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,146 @@
+package gohandlr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type pet struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type putPetInput struct {
+	ID   int
+	Body pet
+}
+
+func TestClientDo(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(Pattern("POST", "/pets/{id}", HandlerWithRequestWithResponse(func(ctx context.Context, req putPetInput) (pet, error) {
+		return pet{ID: req.ID, Name: req.Body.Name}, nil
+	}, WithParamsReader(func(r *http.Request, v interface{}) error {
+		req := v.(*putPetInput)
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("X-Trace") != "1" {
+			return ErrorUnauthorized(errors.New("missing token"))
+		}
+		return ReadPathParam(r.PathValue("id"), Param{Name: "id", In: "path", Style: "simple", Required: true}, &req.ID)
+	}))))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	auth := func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer token")
+		return nil
+	}
+	trace := func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("X-Trace", "1")
+		return nil
+	}
+	c := NewClient(server.URL+"/", WithHTTPClient(server.Client()), WithRequestEditor(auth))
+
+	request := Request{
+		Method: "POST",
+		Path:   "/pets/{id}",
+		Params: []ParamValue{{Param{Name: "id", In: "path", Style: "simple", Required: true}, 7}},
+		Body:   pet{Name: "Rex"},
+	}
+	var created pet
+	if err := c.Do(context.Background(), request, &created, trace); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	if expected := (pet{ID: 7, Name: "Rex"}); created != expected {
+		t.Errorf("Expected response: %v, got: %v", expected, created)
+	}
+
+	// Without the editor of the call, the server refuses the request
+	err := c.Do(context.Background(), request, &created)
+	var responseErr *ResponseError
+	if !errors.As(err, &responseErr) {
+		t.Fatalf("Expected a response error, got: %v", err)
+	}
+	if responseErr.StatusCode != http.StatusUnauthorized || responseErr.Status() != http.StatusUnauthorized {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusUnauthorized, responseErr.StatusCode)
+	}
+	if expected := "401 Unauthorized: failed to read parameters: missing token"; err.Error() != expected {
+		t.Errorf("Expected error: %s, got: %s", expected, err)
+	}
+
+	// A failing editor stops the request
+	failing := func(ctx context.Context, req *http.Request) error {
+		return errors.New("no token")
+	}
+	if err := c.Do(context.Background(), request, &created, failing); err == nil || err.Error() != "no token" {
+		t.Errorf("Expected the error of the editor, got: %v", err)
+	}
+}
+
+func TestClientDoFile(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	server := httptest.NewServer(fileHandler(modTime))
+	defer server.Close()
+
+	var file File
+	if err := NewClient(server.URL).Do(context.Background(), Request{Method: "GET", Path: "/"}, &file); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	content, _ := io.ReadAll(file.Content)
+	if string(content) != "hello world" {
+		t.Errorf("Expected content: %q, got: %q", "hello world", content)
+	}
+	if file.Name != "hello.txt" || file.ContentType != "text/plain" || file.ETag != `"v1"` {
+		t.Errorf("Expected the headers of the file, got: %+v", file)
+	}
+	if !file.ModTime.Equal(modTime) {
+		t.Errorf("Expected modification time: %s, got: %s", modTime, file.ModTime)
+	}
+}
+
+func TestClientDecodeError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/conflict", func(w http.ResponseWriter, r *http.Request) {
+		var err conflict
+		err.Body.Code = "taken"
+		writeError(w, err, http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/invalid", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not json", http.StatusConflict)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	decodeError := func(status int, body []byte) error {
+		switch status {
+		case http.StatusConflict:
+			var e conflict
+			if json.Unmarshal(body, &e.Body) == nil {
+				return e
+			}
+		}
+		return nil
+	}
+	c := NewClient(server.URL)
+
+	err := c.Do(context.Background(), Request{Method: "GET", Path: "/conflict", DecodeError: decodeError}, nil)
+	var conflictErr conflict
+	if !errors.As(err, &conflictErr) || conflictErr.Body.Code != "taken" {
+		t.Errorf("Expected a conflict with code: taken, got: %v", err)
+	}
+
+	// Bodies that don't decode and other statuses are response errors
+	var responseErr *ResponseError
+	err = c.Do(context.Background(), Request{Method: "GET", Path: "/invalid", DecodeError: decodeError}, nil)
+	if !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusConflict {
+		t.Errorf("Expected a response error with status code: %d, got: %v", http.StatusConflict, err)
+	}
+	err = c.Do(context.Background(), Request{Method: "GET", Path: "/missing", DecodeError: decodeError}, nil)
+	if !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a response error with status code: %d, got: %v", http.StatusNotFound, err)
+	}
+}
@@ -0,0 +1,319 @@
+package gohandlr
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder implements a content coding, like gzip, for response and request bodies
+type Encoder interface {
+	// Encoding is the content coding token used in Accept-Encoding and Content-Encoding
+	Encoding() string
+	// Encode writes the compressed form of p to w
+	Encode(w io.Writer, p []byte) error
+	// Decode returns a reader that decompresses r. Closing it releases the
+	// reader, but doesn't close r.
+	Decode(r io.Reader) (io.ReadCloser, error)
+}
+
+// Compression configures when marshaled responses are compressed
+type Compression struct {
+	// MinSize is the smallest body, in bytes, that is compressed
+	MinSize int
+	// ContentTypes that may be compressed. Entries ending in "/" match a
+	// whole type, like "text/", and entries starting with "+" match a
+	// structured syntax suffix, like "+json".
+	ContentTypes []string
+}
+
+var DefaultCompression = Compression{
+	MinSize: 1024,
+	ContentTypes: []string{
+		"text/",
+		"application/json",
+		"application/xml",
+		"application/javascript",
+		"image/svg+xml",
+		"+json",
+		"+xml",
+	},
+}
+
+// WithCompression compresses marshaled responses with the configured
+// encoders, negotiated through the Accept-Encoding header
+func WithCompression(compression Compression) Option {
+	return func(c *Config) {
+		c.Compression = &compression
+	}
+}
+
+// WithEncoder adds a content coding, replacing any encoder for the same coding.
+// Encoders added later are preferred less when a client accepts several.
+func WithEncoder(encoder Encoder) Option {
+	return func(c *Config) {
+		encoders := make([]Encoder, 0, len(c.Encoders)+1)
+		for _, e := range c.Encoders {
+			if e.Encoding() != encoder.Encoding() {
+				encoders = append(encoders, e)
+			}
+		}
+		c.Encoders = append(encoders, encoder)
+	}
+}
+
+// WithMaxBodySize limits the size of request bodies, after decompression
+func WithMaxBodySize(size int64) Option {
+	return func(c *Config) {
+		c.MaxBodySize = size
+	}
+}
+
+// GzipEncoder creates a gzip Encoder with pooled writers and readers at the
+// given compression level
+func GzipEncoder(level int) Encoder {
+	return &gzipEncoder{level: level}
+}
+
+type gzipEncoder struct {
+	level   int
+	pool    sync.Pool
+	readers sync.Pool
+}
+
+func (e *gzipEncoder) Encoding() string {
+	return "gzip"
+}
+
+func (e *gzipEncoder) Encode(w io.Writer, p []byte) error {
+	gw, ok := e.pool.Get().(*gzip.Writer)
+	if ok {
+		gw.Reset(w)
+	} else {
+		var err error
+		gw, err = gzip.NewWriterLevel(w, e.level)
+		if err != nil {
+			return err
+		}
+	}
+	defer e.pool.Put(gw)
+
+	if _, err := gw.Write(p); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func (e *gzipEncoder) Decode(r io.Reader) (io.ReadCloser, error) {
+	gr, ok := e.readers.Get().(*gzip.Reader)
+	if !ok {
+		var err error
+		if gr, err = gzip.NewReader(r); err != nil {
+			return nil, err
+		}
+	} else if err := gr.Reset(r); err != nil {
+		e.readers.Put(gr)
+		return nil, err
+	}
+	return &pooledReader{ReadCloser: gr, pool: &e.readers}, nil
+}
+
+// DeflateEncoder creates a deflate Encoder with pooled writers and readers
+// at the given compression level. HTTP's deflate coding is the zlib format of RFC 1950,
+// not raw DEFLATE.
+func DeflateEncoder(level int) Encoder {
+	return &deflateEncoder{level: level}
+}
+
+type deflateEncoder struct {
+	level   int
+	pool    sync.Pool
+	readers sync.Pool
+}
+
+func (e *deflateEncoder) Encoding() string {
+	return "deflate"
+}
+
+func (e *deflateEncoder) Encode(w io.Writer, p []byte) error {
+	zw, ok := e.pool.Get().(*zlib.Writer)
+	if ok {
+		zw.Reset(w)
+	} else {
+		var err error
+		zw, err = zlib.NewWriterLevel(w, e.level)
+		if err != nil {
+			return err
+		}
+	}
+	defer e.pool.Put(zw)
+
+	if _, err := zw.Write(p); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (e *deflateEncoder) Decode(r io.Reader) (io.ReadCloser, error) {
+	zr, ok := e.readers.Get().(io.ReadCloser)
+	if !ok {
+		var err error
+		if zr, err = zlib.NewReader(r); err != nil {
+			return nil, err
+		}
+	} else if err := zr.(zlib.Resetter).Reset(r, nil); err != nil {
+		e.readers.Put(zr)
+		return nil, err
+	}
+	return &pooledReader{ReadCloser: zr, pool: &e.readers}, nil
+}
+
+// pooledReader is a decompressing reader that Close returns to its pool
+type pooledReader struct {
+	io.ReadCloser
+	pool   *sync.Pool
+	closed bool
+}
+
+func (r *pooledReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	err := r.ReadCloser.Close()
+	r.pool.Put(r.ReadCloser)
+	return err
+}
+
+// decodedBody is a decompressed request body. Closing it closes the
+// decoder and the body it reads.
+type decodedBody struct {
+	io.ReadCloser
+	body io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	return errors.Join(b.ReadCloser.Close(), b.body.Close())
+}
+
+// encoder returns the encoder for a content coding
+func (c *Config) encoder(encoding string) Encoder {
+	for _, e := range c.Encoders {
+		if strings.EqualFold(e.Encoding(), encoding) {
+			return e
+		}
+	}
+	return nil
+}
+
+// codingETag is the tag of a representation encoded with a content coding
+func codingETag(etag, encoding string) string {
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+// withoutCoding removes the suffix codingETag added to a tag for encoding
+func withoutCoding(etag, encoding string) string {
+	if suffix := "-" + encoding + `"`; strings.HasSuffix(etag, suffix) {
+		return strings.TrimSuffix(etag, suffix) + `"`
+	}
+	return etag
+}
+
+// decodeBody replaces the request body with its decompressed form and
+// applies the body size limit
+func (c *Config) decodeBody(r *http.Request) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	encoding := strings.TrimSpace(r.Header.Get("Content-Encoding"))
+	if encoding != "" && !strings.EqualFold(encoding, "identity") {
+		encoder := c.encoder(encoding)
+		if encoder == nil {
+			return ErrorUnsupportedMediaType(fmt.Errorf("unsupported content encoding %q", encoding))
+		}
+		body, err := encoder.Decode(r.Body)
+		if err != nil {
+			return ErrorBadRequest(fmt.Errorf("failed to decode %s body: %w", encoding, err))
+		}
+		r.Body = &decodedBody{ReadCloser: body, body: r.Body}
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+	}
+
+	if c.MaxBodySize > 0 {
+		r.Body = http.MaxBytesReader(nil, r.Body, c.MaxBodySize)
+	}
+	return nil
+}
+
+// negotiateEncoding picks the encoder for a response body, or nil to send it as-is
+func (c *Config) negotiateEncoding(r *http.Request, contentType string, size int) Encoder {
+	if c.Compression == nil || size < c.Compression.MinSize || !compressible(c.Compression.ContentTypes, contentType) {
+		return nil
+	}
+
+	accepted := parseAcceptEncoding(r.Header.Get("Accept-Encoding"))
+	var best Encoder
+	bestQ := 0.0
+	for _, e := range c.Encoders {
+		q, ok := accepted[strings.ToLower(e.Encoding())]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best
+}
+
+// parseAcceptEncoding maps each coding in an Accept-Encoding header to its quality
+func parseAcceptEncoding(header string) map[string]float64 {
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[coding] = q
+	}
+	return accepted
+}
+
+func compressible(allowed []string, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, a := range allowed {
+		switch {
+		case strings.HasSuffix(a, "/"):
+			if strings.HasPrefix(mediaType, a) {
+				return true
+			}
+		case strings.HasPrefix(a, "+"):
+			if strings.HasSuffix(mediaType, a) {
+				return true
+			}
+		case mediaType == a:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package gohandlr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type compressBody struct {
+	Body struct {
+		Name string `json:"name"`
+	}
+}
+
+func compressHandler(name string) http.HandlerFunc {
+	return HandlerNoRequestWithResponse(func(ctx context.Context) (etagUser, error) {
+		return etagUser{Name: name}, nil
+	}, WithCompression(DefaultCompression))
+}
+
+func TestCompressionGzip(t *testing.T) {
+	name := strings.Repeat("gopher", 500)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "deflate;q=0.5, gzip")
+	rec := httptest.NewRecorder()
+	compressHandler(name)(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Expected content encoding: %s, got: %s", "gzip", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Errorf("Expected vary: %s, got: %s", "Accept-Encoding", got)
+	}
+
+	gr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	body, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := `{"name":"` + name + "\"}\n"; string(body) != want {
+		t.Errorf("Expected decompressed body of %d bytes, got: %d bytes", len(want), len(body))
+	}
+}
+
+func TestCompressionDeflate(t *testing.T) {
+	name := strings.Repeat("gopher", 500)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "deflate")
+	rec := httptest.NewRecorder()
+	compressHandler(name)(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "deflate" {
+		t.Fatalf("Expected content encoding: %s, got: %s", "deflate", got)
+	}
+
+	// The deflate coding of HTTP is zlib
+	zr, err := zlib.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := `{"name":"` + name + "\"}\n"; string(body) != want {
+		t.Errorf("Expected decompressed body of %d bytes, got: %d bytes", len(want), len(body))
+	}
+}
+
+func TestCompressionNegotiation(t *testing.T) {
+	large := strings.Repeat("gopher", 500)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		encoding       string
+	}{
+		{large, "", ""},
+		{large, "deflate", "deflate"},
+		{large, "gzip;q=0.2, deflate;q=0.8", "deflate"},
+		{large, "*", "gzip"},
+		{large, "*, gzip;q=0", "deflate"},
+		{large, "br", ""},
+		{"gopher", "gzip", ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		rec := httptest.NewRecorder()
+		compressHandler(test.name)(rec, req)
+
+		if got := rec.Header().Get("Content-Encoding"); got != test.encoding {
+			t.Errorf("Accept-Encoding %q: expected content encoding: %q, got: %q", test.acceptEncoding, test.encoding, got)
+		}
+	}
+}
+
+func TestRequestDecompression(t *testing.T) {
+	var compressed bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&compressed, zlib.BestSpeed)
+	zw.Write([]byte(`{"name":"gopher"}`))
+	zw.Close()
+
+	var got string
+	handler := HandlerWithRequestNoResponse(func(ctx context.Context, req compressBody) error {
+		got = req.Body.Name
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", &compressed)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "deflate")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusNoContent, rec.Code)
+	}
+	if got != "gopher" {
+		t.Errorf("Expected name: %s, got: %s", "gopher", got)
+	}
+}
+
+func TestRequestDecompressionLimit(t *testing.T) {
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	gw.Write([]byte(`{"name":"` + strings.Repeat("a", 10000) + `"}`))
+	gw.Close()
+
+	handler := HandlerWithRequestNoResponse(func(ctx context.Context, req compressBody) error {
+		return nil
+	}, WithMaxBodySize(1024))
+
+	req := httptest.NewRequest(http.MethodPost, "/", &compressed)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusRequestEntityTooLarge, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "br")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusUnsupportedMediaType, rec.Code)
+	}
+}
+
+// closeRecorder records whether a request body was closed
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestRequestDecompressionClose(t *testing.T) {
+	config := NewConfig()
+	for _, encoding := range []string{"gzip", "deflate", "gzip"} {
+		var compressed bytes.Buffer
+		config.encoder(encoding).Encode(&compressed, []byte(`{"name":"gopher"}`))
+		body := &closeRecorder{Reader: &compressed}
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Body = body
+		req.Header.Set("Content-Encoding", encoding)
+		if err := config.decodeBody(req); err != nil {
+			t.Fatalf("%s: unexpected error: %v", encoding, err)
+		}
+		if content, err := io.ReadAll(req.Body); err != nil || string(content) != `{"name":"gopher"}` {
+			t.Errorf("%s: expected the decompressed body, got: %q (%v)", encoding, content, err)
+		}
+		if err := req.Body.Close(); err != nil {
+			t.Errorf("%s: unexpected error: %v", encoding, err)
+		}
+		if !body.closed {
+			t.Errorf("%s: expected closing the decompressed body to close the request body", encoding)
+		}
+	}
+}
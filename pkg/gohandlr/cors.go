@@ -0,0 +1,163 @@
+package gohandlr
+
+import (
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS configures cross-origin requests from browsers
+type CORS struct {
+	// AllowedOrigins are exact origins, "*" for any origin, or patterns like
+	// "https://*.example.com"
+	AllowedOrigins []string
+	// AllowedMethods defaults to the methods the path defines
+	AllowedMethods []string
+	// AllowedHeaders defaults to any header the preflight request asks for
+	AllowedHeaders []string
+	// ExposedHeaders are response headers the browser lets scripts read
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// WithCORS adds CORS headers to responses for allowed origins. Preflight
+// requests are answered by OptionsHandler.
+func WithCORS(cors CORS) Option {
+	return func(c *Config) {
+		c.CORS = &cors
+	}
+}
+
+// OptionsHandler answers OPTIONS requests for a path with the methods it
+// defines in the Allow header, and answers CORS preflight requests when the
+// options include WithCORS.
+func OptionsHandler(methods []string, options ...Option) http.HandlerFunc {
+	config := NewConfig(options...)
+	allow := strings.Join(allowedMethods(methods), ", ")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		config.setVersion(w)
+		w.Header().Set("Allow", allow)
+		if config.CORS != nil && r.Header.Get("Access-Control-Request-Method") != "" {
+			config.CORS.preflight(w, r, methods)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// allowedMethods adds the methods answered for every path to methods
+func allowedMethods(methods []string) []string {
+	allowed := slices.Clone(methods)
+	if slices.Contains(allowed, http.MethodGet) && !slices.Contains(allowed, http.MethodHead) {
+		allowed = append(allowed, http.MethodHead)
+	}
+	if !slices.Contains(allowed, http.MethodOptions) {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	return allowed
+}
+
+func (c *CORS) middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.setOrigin(w, r)
+		if len(c.ExposedHeaders) > 0 && w.Header().Get("Access-Control-Allow-Origin") != "" {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(c.ExposedHeaders, ", "))
+		}
+		next(w, r)
+	}
+}
+
+// preflight answers a CORS preflight request. A request that isn't allowed
+// gets no CORS headers, which makes the browser reject it.
+func (c *CORS) preflight(w http.ResponseWriter, r *http.Request, methods []string) {
+	header := w.Header()
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	allowed := c.AllowedMethods
+	if len(allowed) == 0 {
+		allowed = methods
+	}
+	method := r.Header.Get("Access-Control-Request-Method")
+	if !slices.Contains(allowed, method) {
+		header.Add("Vary", "Origin")
+		return
+	}
+
+	var requested []string
+	for _, h := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			requested = append(requested, h)
+		}
+	}
+	if len(c.AllowedHeaders) > 0 {
+		for _, h := range requested {
+			if !slices.ContainsFunc(c.AllowedHeaders, func(allowed string) bool {
+				return strings.EqualFold(allowed, h)
+			}) {
+				header.Add("Vary", "Origin")
+				return
+			}
+		}
+	}
+
+	if !c.setOrigin(w, r) {
+		return
+	}
+	header.Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+	if len(requested) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if c.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(seconds(c.MaxAge)))
+	}
+}
+
+// setOrigin sets the allowed origin headers and reports whether the origin is allowed
+func (c *CORS) setOrigin(w http.ResponseWriter, r *http.Request) bool {
+	header := w.Header()
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+
+	anyOrigin := slices.Contains(c.AllowedOrigins, "*")
+	if !anyOrigin || c.AllowCredentials {
+		header.Add("Vary", "Origin")
+	}
+	if !c.originAllowed(origin) {
+		return false
+	}
+
+	// Credentialed requests can't use the wildcard
+	if anyOrigin && !c.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+func (c *CORS) originAllowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range c.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		if strings.Contains(allowed, "*") {
+			if ok, _ := path.Match(allowed, origin); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package gohandlr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testCORS = CORS{
+	AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+	AllowedHeaders:   []string{"Content-Type", "Authorization"},
+	ExposedHeaders:   []string{"ETag"},
+	AllowCredentials: true,
+	MaxAge:           10 * time.Minute,
+}
+
+func preflight(origin, method, headers string) *http.Request {
+	req := httptest.NewRequest(http.MethodOptions, "/users", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	return req
+}
+
+func TestOptionsHandlerPreflight(t *testing.T) {
+	handler := OptionsHandler([]string{http.MethodGet, http.MethodPut}, WithCORS(testCORS))
+
+	rec := httptest.NewRecorder()
+	handler(rec, preflight("https://api.example.org", http.MethodPut, "content-type"))
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusNoContent, rec.Code)
+	}
+	expected := map[string]string{
+		"Allow":                            "GET, PUT, HEAD, OPTIONS",
+		"Access-Control-Allow-Origin":      "https://api.example.org",
+		"Access-Control-Allow-Methods":     "GET, PUT",
+		"Access-Control-Allow-Headers":     "content-type",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	}
+	for name, value := range expected {
+		if got := rec.Header().Get(name); got != value {
+			t.Errorf("Expected %s: %q, got: %q", name, value, got)
+		}
+	}
+
+	rejected := []*http.Request{
+		preflight("https://evil.example.com", http.MethodPut, ""),
+		preflight("https://app.example.com", http.MethodDelete, ""),
+		preflight("https://app.example.com", http.MethodPut, "X-Custom"),
+	}
+	for _, req := range rejected {
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Expected no allowed origin for %s %s, got: %s", req.Header.Get("Origin"), req.Header.Get("Access-Control-Request-Method"), got)
+		}
+	}
+}
+
+func TestWithCORS(t *testing.T) {
+	handler := HandlerNoRequestNoResponse(func(ctx context.Context) error {
+		return nil
+	}, WithCORS(CORS{AllowedOrigins: []string{"*"}, ExposedHeaders: []string{"ETag"}}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Origin", "https://anywhere.test")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Expected allowed origin: %s, got: %s", "*", got)
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); got != "ETag" {
+		t.Errorf("Expected exposed headers: %s, got: %s", "ETag", got)
+	}
+
+	// Errors carry CORS headers too
+	handler = HandlerNoRequestNoResponse(func(ctx context.Context) error {
+		return nil
+	}, WithCORS(testCORS), WithSecurity(SecurityRequirement{"bearer": {}}), WithSchemes(map[string]Scheme{
+		"bearer": BearerScheme(func(ctx context.Context, token string, scopes []string) (any, error) {
+			return token, nil
+		}),
+	}))
+
+	req = httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusUnauthorized, rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected allowed origin: %s, got: %s", "https://app.example.com", got)
+	}
+}
@@ -0,0 +1,76 @@
+package gohandlr
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"net/http"
+)
+
+//go:embed docs.html
+var docsHTML string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsHTML))
+
+// Document is an OpenAPI document embedded in a generated package
+type Document struct {
+	// JSON is the bundled document as JSON
+	JSON []byte
+	// YAML is the bundled document as YAML
+	YAML  []byte
+	Title string
+	// Version is the info.version of the document
+	Version string
+}
+
+// WithVersion publishes the version of the API in the API-Version header of every response
+func WithVersion(version string) Option {
+	return func(c *Config) {
+		c.Version = version
+	}
+}
+
+// WithDocs makes RegisterHandlers serve the OpenAPI document at /openapi.json
+// and /openapi.yaml, and a docs page for it at /docs
+func WithDocs() Option {
+	return func(c *Config) {
+		c.Docs = true
+	}
+}
+
+// Validator creates an OpenAPIValidator for the document
+func (d Document) Validator() (*OpenAPIValidator, error) {
+	return LoadOpenAPIValidator(d.JSON)
+}
+
+// JSONHandler serves the document as JSON
+func (d Document) JSONHandler(options ...Option) http.HandlerFunc {
+	return d.handler("application/json", d.JSON, options)
+}
+
+// YAMLHandler serves the document as YAML
+func (d Document) YAMLHandler(options ...Option) http.HandlerFunc {
+	return d.handler("application/yaml", d.YAML, options)
+}
+
+// DocsHandler serves a docs page for the document. The page has no external
+// assets, so it works offline, and reads the document from openapi.json
+// next to it.
+func (d Document) DocsHandler(options ...Option) http.HandlerFunc {
+	var page bytes.Buffer
+	if err := docsTemplate.Execute(&page, d); err != nil {
+		panic(err)
+	}
+	return d.handler("text/html; charset=utf-8", page.Bytes(), options)
+}
+
+func (d Document) handler(contentType string, content []byte, options []Option) http.HandlerFunc {
+	etag := hashETag(content)
+	return NewConfig(options...).wrap(func(w http.ResponseWriter, r *http.Request) {
+		File{
+			Content:     bytes.NewReader(content),
+			ContentType: contentType,
+			ETag:        etag,
+		}.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,63 @@
+package gohandlr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testDocument = Document{
+	JSON:    []byte(`{"openapi":"3.0.3","info":{"title":"Users","version":"1.2.0"},"paths":{}}`),
+	YAML:    []byte("openapi: 3.0.3\ninfo:\n  title: Users\n  version: 1.2.0\npaths: {}\n"),
+	Title:   "Users <API>",
+	Version: "1.2.0",
+}
+
+func TestDocumentHandlers(t *testing.T) {
+	tests := []struct {
+		handler     http.HandlerFunc
+		contentType string
+		body        string
+	}{
+		{testDocument.JSONHandler(), "application/json", string(testDocument.JSON)},
+		{testDocument.YAMLHandler(), "application/yaml", string(testDocument.YAML)},
+		{testDocument.DocsHandler(), "text/html; charset=utf-8", "<h1>Users &lt;API&gt;<small>1.2.0</small></h1>"},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		test.handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code: %d, got: %d", http.StatusOK, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Type"); got != test.contentType {
+			t.Errorf("Expected content type: %s, got: %s", test.contentType, got)
+		}
+		if !strings.Contains(rec.Body.String(), test.body) {
+			t.Errorf("Expected the body to contain: %s, got: %s", test.body, rec.Body.String())
+		}
+		if rec.Header().Get("ETag") == "" {
+			t.Errorf("Expected an ETag")
+		}
+	}
+
+	if _, err := testDocument.Validator(); err != nil {
+		t.Errorf("Expected a validator, got: %v", err)
+	}
+}
+
+func TestWithVersion(t *testing.T) {
+	handlers := []http.HandlerFunc{
+		testDocument.JSONHandler(WithVersion("1.2.0")),
+		MethodNotAllowedHandler([]string{http.MethodGet}, WithVersion("1.2.0")),
+	}
+	for _, handler := range handlers {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+		if got := rec.Header().Get("API-Version"); got != "1.2.0" {
+			t.Errorf("Expected API version: %s, got: %s", "1.2.0", got)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package gohandlr
 
-import "net/http"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
 
 type Error interface {
 	Error() string
@@ -20,6 +25,10 @@ func (e NewError) Status() int {
 	return e.status
 }
 
+func (e NewError) Unwrap() error {
+	return e.err
+}
+
 func ErrorInternal(err error) Error {
 	return NewError{err: err, status: http.StatusInternalServerError}
 }
@@ -35,3 +44,88 @@ func ErrorUnavailable(err error) Error {
 func ErrorTimeout(err error) Error {
 	return NewError{err: err, status: http.StatusGatewayTimeout}
 }
+
+func ErrorBadRequest(err error) Error {
+	return NewError{err: err, status: http.StatusBadRequest}
+}
+
+func ErrorUnauthorized(err error) Error {
+	return NewError{err: err, status: http.StatusUnauthorized}
+}
+
+func ErrorForbidden(err error) Error {
+	return NewError{err: err, status: http.StatusForbidden}
+}
+
+func ErrorMethodNotAllowed(err error) Error {
+	return NewError{err: err, status: http.StatusMethodNotAllowed}
+}
+
+func ErrorConflict(err error) Error {
+	return NewError{err: err, status: http.StatusConflict}
+}
+
+func ErrorUnprocessableEntity(err error) Error {
+	return NewError{err: err, status: http.StatusUnprocessableEntity}
+}
+
+func ErrorTooManyRequests(err error) Error {
+	return NewError{err: err, status: http.StatusTooManyRequests}
+}
+
+func ErrorPreconditionFailed(err error) Error {
+	return NewError{err: err, status: http.StatusPreconditionFailed}
+}
+
+func ErrorRequestEntityTooLarge(err error) Error {
+	return NewError{err: err, status: http.StatusRequestEntityTooLarge}
+}
+
+func ErrorUnsupportedMediaType(err error) Error {
+	return NewError{err: err, status: http.StatusUnsupportedMediaType}
+}
+
+func ErrorNotImplemented(err error) Error {
+	return NewError{err: err, status: http.StatusNotImplemented}
+}
+
+// BodyError is an Error with a body of its own, which is written as JSON
+// instead of the text of the error. The error types generated from the 4XX
+// and 5XX responses of a spec implement it.
+type BodyError interface {
+	Error
+	ErrorBody() any
+}
+
+// ErrorText is the text of an error response with a body: its status
+// followed by the JSON of body
+func ErrorText(status int, body any) string {
+	text := fmt.Sprintf("%d %s", status, http.StatusText(status))
+	if content, err := json.Marshal(body); err == nil && string(content) != "null" {
+		text += ": " + string(content)
+	}
+	return text
+}
+
+// writeError writes err to w. Errors that implement Error are written with
+// their own status code, anything else with the given status. A BodyError
+// is written with its body.
+func writeError(w http.ResponseWriter, err error, status int) {
+	var bodyErr BodyError
+	if errors.As(err, &bodyErr) {
+		content, marshalErr := json.Marshal(bodyErr.ErrorBody())
+		if marshalErr == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			w.WriteHeader(bodyErr.Status())
+			w.Write(append(content, '\n'))
+			return
+		}
+	}
+
+	var e Error
+	if errors.As(err, &e) {
+		status = e.Status()
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -2,7 +2,9 @@ package gohandlr
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -33,6 +35,19 @@ func TestErrorInternal(t *testing.T) {
 	}
 }
 
+func TestErrorNotImplemented(t *testing.T) {
+	err := errors.New("not implemented error")
+	notImplementedErr := ErrorNotImplemented(err)
+
+	if notImplementedErr.Error() != err.Error() {
+		t.Errorf("Expected error message: %s, got: %s", err.Error(), notImplementedErr.Error())
+	}
+
+	if notImplementedErr.Status() != http.StatusNotImplemented {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusNotImplemented, notImplementedErr.Status())
+	}
+}
+
 func TestErrorBadGateway(t *testing.T) {
 	err := errors.New("bad gateway error")
 	badGatewayErr := ErrorBadGateway(err)
@@ -71,3 +86,34 @@ func TestErrorTimeout(t *testing.T) {
 		t.Errorf("Expected status code: %d, got: %d", http.StatusGatewayTimeout, timeoutErr.Status())
 	}
 }
+
+type conflict struct {
+	Body struct {
+		Code string `json:"code"`
+	}
+}
+
+func (e conflict) Error() string  { return ErrorText(e.Status(), e.Body) }
+func (e conflict) Status() int    { return http.StatusConflict }
+func (e conflict) ErrorBody() any { return e.Body }
+
+func TestWriteBodyError(t *testing.T) {
+	var err conflict
+	err.Body.Code = "taken"
+
+	if expected := `409 Conflict: {"code":"taken"}`; err.Error() != expected {
+		t.Errorf("Expected error message: %s, got: %s", expected, err.Error())
+	}
+
+	rec := httptest.NewRecorder()
+	writeError(rec, fmt.Errorf("saving: %w", err), http.StatusInternalServerError)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusConflict, rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Expected content type: %s, got: %s", "application/json", got)
+	}
+	if expected := "{\"code\":\"taken\"}\n"; rec.Body.String() != expected {
+		t.Errorf("Expected body: %q, got: %q", expected, rec.Body.String())
+	}
+}
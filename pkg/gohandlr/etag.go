@@ -0,0 +1,215 @@
+package gohandlr
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Envelope wraps a response body with metadata that is written alongside it
+type Envelope[T any] struct {
+	Body T
+	// ETag is sent as the entity tag of the response instead of a hash of the body
+	ETag string
+	// Version is an opaque version of the resource. It is sent as a strong
+	// entity tag when ETag is empty.
+	Version string
+}
+
+func (e Envelope[T]) envelope() (interface{}, string) {
+	etag := e.ETag
+	if etag == "" {
+		etag = e.Version
+	}
+	if etag != "" {
+		etag = quoteETag(etag)
+	}
+	return &e.Body, etag
+}
+
+// enveloped is implemented by Envelope for every body type
+type enveloped interface {
+	envelope() (body interface{}, etag string)
+}
+
+// WithETag hashes marshaled responses into a strong ETag and answers GET and
+// HEAD requests with a matching If-None-Match with 304 Not Modified
+func WithETag() Option {
+	return func(c *Config) {
+		c.ETag = true
+	}
+}
+
+// IfMatch checks the If-Match precondition of the request against the current
+// entity tag of the resource. Process functions of write methods should call
+// it before changing anything and return the error, which replies with 412
+// Precondition Failed. An empty current tag means the resource doesn't exist.
+// Handlers with WithCurrentETag check it before the process function runs.
+func IfMatch(ctx context.Context, current string) error {
+	p, ok := ctx.Value(preconditionsKey{}).(preconditions)
+	if !ok {
+		return nil
+	}
+	if current != "" {
+		current = quoteETag(current)
+	}
+	return p.ifMatchError(current)
+}
+
+// WithCurrentETag checks the If-Match precondition of unsafe requests
+// before the process function runs, against the entity tag current returns
+// for the request. It returns the tag the GET of the resource sends, like the
+// Version of its Envelope, or an empty tag when the resource doesn't exist.
+// An error of current fails the request with its status, or 412 when it has
+// none, so a write never goes ahead unchecked.
+func WithCurrentETag[Request any](current func(ctx context.Context, req Request) (string, error)) Option {
+	return func(c *Config) {
+		c.CurrentETag = func(ctx context.Context, req any) (string, error) {
+			r, _ := req.(Request)
+			return current(ctx, r)
+		}
+	}
+}
+
+type preconditionsKey struct{}
+
+type preconditions struct {
+	ifMatch string
+	// encodings are the content codings, whose names compressed responses
+	// add to their tags
+	encodings []string
+}
+
+// ifMatchError checks If-Match against the quoted current tag, which is
+// empty when the resource doesn't exist. The tags this server sends for
+// compressed representations, the current tag with the name of the coding,
+// match too.
+func (p preconditions) ifMatchError(current string) error {
+	if p.ifMatch == "" {
+		return nil
+	}
+	if current != "" {
+		if etagMatch(p.ifMatch, current, false) {
+			return nil
+		}
+		for _, encoding := range p.encodings {
+			if etagMatch(p.ifMatch, codingETag(current, encoding), false) {
+				return nil
+			}
+		}
+	}
+	return ErrorPreconditionFailed(errors.New("precondition failed: If-Match does not match"))
+}
+
+// withPreconditions stores the conditional headers of write requests on the context
+func (c *Config) withPreconditions(r *http.Request) *http.Request {
+	if isSafeMethod(r.Method) {
+		return r
+	}
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return r
+	}
+	p := preconditions{ifMatch: ifMatch}
+	for _, e := range c.Encoders {
+		p.encodings = append(p.encodings, e.Encoding())
+	}
+	ctx := context.WithValue(r.Context(), preconditionsKey{}, p)
+	return r.WithContext(ctx)
+}
+
+// checkCurrentETag checks the If-Match precondition of a request against
+// the tag CurrentETag looks up for req. When the tag can't be looked up, the
+// error of the lookup is returned rather than letting the request through.
+func (c *Config) checkCurrentETag(ctx context.Context, req any) error {
+	p, ok := ctx.Value(preconditionsKey{}).(preconditions)
+	if !ok || c.CurrentETag == nil {
+		return nil
+	}
+
+	current, err := c.CurrentETag(ctx, req)
+	if err != nil {
+		var e Error
+		if errors.As(err, &e) && (e.Status() == http.StatusNotFound || e.Status() == http.StatusGone) {
+			// The resource doesn't exist, so no tag matches
+			return p.ifMatchError("")
+		}
+		return err
+	}
+	if current != "" {
+		current = quoteETag(current)
+	}
+	return p.ifMatchError(current)
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// hashETag creates a strong entity tag from a response body
+func hashETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatch reports whether etag is in the comma separated list of an
+// If-Match or If-None-Match header. Weak comparison ignores the W/ prefix.
+func etagMatch(header, etag string, weak bool) bool {
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	} else if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter holds a marshaled response so it can be inspected before
+// it is sent. Headers are written straight to the wrapped ResponseWriter.
+type bufferedWriter struct {
+	w      http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+func (b *bufferedWriter) Header() http.Header {
+	return b.w.Header()
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	return b.buf.Write(p)
+}
+
+func (b *bufferedWriter) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+// flush writes the buffered status and body to the wrapped ResponseWriter
+func (b *bufferedWriter) flush() error {
+	if b.status != 0 {
+		b.w.WriteHeader(b.status)
+	}
+	_, err := b.w.Write(b.buf.Bytes())
+	return err
+}
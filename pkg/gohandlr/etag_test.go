@@ -0,0 +1,185 @@
+package gohandlr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type etagUser struct {
+	Name string `json:"name"`
+}
+
+func TestWithETag(t *testing.T) {
+	handler := HandlerNoRequestWithResponse(func(ctx context.Context) (etagUser, error) {
+		return etagUser{Name: "gopher"}, nil
+	}, WithETag())
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("Expected an ETag header")
+	}
+	if rec.Body.String() != "{\"name\":\"gopher\"}\n" {
+		t.Errorf("Expected body: %q, got: %q", "{\"name\":\"gopher\"}\n", rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"other", `+etag)
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusNotModified, rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("Expected an empty body, got: %q", rec.Body.String())
+	}
+}
+
+func TestEnvelopeVersion(t *testing.T) {
+	handler := HandlerNoRequestWithResponse(func(ctx context.Context) (Envelope[etagUser], error) {
+		return Envelope[etagUser]{Body: etagUser{Name: "gopher"}, Version: "42"}, nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `W/"42"`)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if got := rec.Header().Get("ETag"); got != `"42"` {
+		t.Errorf("Expected etag: %s, got: %s", `"42"`, got)
+	}
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusNotModified, rec.Code)
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	handler := HandlerNoRequestNoResponse(func(ctx context.Context) error {
+		return IfMatch(ctx, "7")
+	})
+
+	tests := []struct {
+		ifMatch string
+		status  int
+	}{
+		{"", http.StatusNoContent},
+		{`"7"`, http.StatusNoContent},
+		{`"6", "7"`, http.StatusNoContent},
+		{"*", http.StatusNoContent},
+		{`"6"`, http.StatusPreconditionFailed},
+		{`W/"7"`, http.StatusPreconditionFailed},
+		// The tags of compressed responses match the tag they were made from
+		{`"7-gzip"`, http.StatusNoContent},
+		{`"6", "7-deflate"`, http.StatusNoContent},
+		{`"7-br"`, http.StatusPreconditionFailed},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/", nil)
+		if test.ifMatch != "" {
+			req.Header.Set("If-Match", test.ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("If-Match %s: expected status code: %d, got: %d", test.ifMatch, test.status, rec.Code)
+		}
+	}
+}
+
+func TestIfMatchCodingSuffix(t *testing.T) {
+	// Only the suffixes this server adds are left out, so a version that
+	// ends like a coding is compared as it is
+	handler := HandlerNoRequestNoResponse(func(ctx context.Context) error {
+		return IfMatch(ctx, "7-gzip")
+	})
+
+	tests := []struct {
+		ifMatch string
+		status  int
+	}{
+		{`"7-gzip"`, http.StatusNoContent},
+		{`"7-gzip-gzip"`, http.StatusNoContent},
+		{`"7"`, http.StatusPreconditionFailed},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/", nil)
+		req.Header.Set("If-Match", test.ifMatch)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("If-Match %s: expected status code: %d, got: %d", test.ifMatch, test.status, rec.Code)
+		}
+	}
+}
+
+func TestWithCurrentETag(t *testing.T) {
+	type itemRequest struct {
+		ID int `json:"id"`
+	}
+
+	var current string
+	var currentErr error
+	var lookedUp int
+	processed := false
+	put := HandlerWithRequestNoResponse(func(ctx context.Context, req itemRequest) error {
+		processed = true
+		return nil
+	}, WithCurrentETag(func(ctx context.Context, req itemRequest) (string, error) {
+		lookedUp = req.ID
+		return current, currentErr
+	}), WithParamsReader(func(r *http.Request, v interface{}) error {
+		v.(*itemRequest).ID = 7
+		return nil
+	}))
+
+	tests := []struct {
+		current string
+		err     error
+		ifMatch string
+		status  int
+	}{
+		{"7", nil, "", http.StatusNoContent},
+		{"7", nil, `"7"`, http.StatusNoContent},
+		{"7", nil, `"7-gzip"`, http.StatusNoContent},
+		{"7", nil, "*", http.StatusNoContent},
+		{"8", nil, `"7-gzip"`, http.StatusPreconditionFailed},
+		{"", nil, "*", http.StatusPreconditionFailed},
+		{"", NewError{err: errors.New("no item"), status: http.StatusNotFound}, "*", http.StatusPreconditionFailed},
+		// A tag that can't be looked up fails the request instead of
+		// letting it through unchecked
+		{"", ErrorTooManyRequests(errors.New("slow down")), `"7"`, http.StatusTooManyRequests},
+		{"", ErrorInternal(errors.New("database down")), `"7"`, http.StatusInternalServerError},
+		{"", errors.New("lookup failed"), `"7"`, http.StatusPreconditionFailed},
+	}
+	for _, test := range tests {
+		current, currentErr = test.current, test.err
+		processed, lookedUp = false, 0
+		req := httptest.NewRequest(http.MethodPut, "/items/7", strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		if test.ifMatch != "" {
+			req.Header.Set("If-Match", test.ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		put(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("Current %q (%v), If-Match %s: expected status code: %d, got: %d", test.current, test.err, test.ifMatch, test.status, rec.Code)
+		}
+		if processed != (test.status == http.StatusNoContent) {
+			t.Errorf("Current %q (%v), If-Match %s: expected the process function to run only when the precondition holds", test.current, test.err, test.ifMatch)
+		}
+		if test.ifMatch != "" && lookedUp != 7 {
+			t.Errorf("If-Match %s: expected the tag of item 7 to be looked up, got: %d", test.ifMatch, lookedUp)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package gohandlr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// File is a response that is streamed to the client as-is instead of being
+// marshaled. Range, If-Range, If-None-Match and If-Modified-Since requests are
+// answered by http.ServeContent.
+//
+// If Content implements io.Closer it is closed once the response is written.
+type File struct {
+	Content     io.ReadSeeker
+	ContentType string
+	// Name is sent as the Content-Disposition filename when set
+	Name string
+	// Inline serves the file with an inline disposition instead of attachment
+	Inline  bool
+	ModTime time.Time
+	ETag    string
+}
+
+// NewFile creates a File response from a seekable reader
+func NewFile(content io.ReadSeeker, name, contentType string) File {
+	return File{
+		Content:     content,
+		Name:        name,
+		ContentType: contentType,
+	}
+}
+
+// NewFSFile creates a File response from an open fs.File. The name and
+// modification time are taken from its FileInfo. Files that can't seek are
+// read into memory and closed.
+func NewFSFile(f fs.File) (File, error) {
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return File{}, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return File{}, fmt.Errorf("%s is a directory", info.Name())
+	}
+
+	file := File{
+		Name:    info.Name(),
+		ModTime: info.ModTime(),
+	}
+
+	if rs, ok := f.(io.ReadSeeker); ok {
+		file.Content = rs
+		return file, nil
+	}
+
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return File{}, err
+	}
+	file.Content = bytes.NewReader(content)
+	return file, nil
+}
+
+// FileFromFS opens name in fsys and creates a File response from it
+func FileFromFS(fsys fs.FS, name string) (File, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return File{}, err
+	}
+	return NewFSFile(f)
+}
+
+// ServeHTTP writes the file to w, honoring range and conditional requests
+func (f File) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if c, ok := f.Content.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	if f.Content == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	header := w.Header()
+	if f.ContentType != "" {
+		header.Set("Content-Type", f.ContentType)
+	}
+	if f.ETag != "" {
+		header.Set("ETag", quoteETag(f.ETag))
+	}
+	if f.Name != "" {
+		disposition := "attachment"
+		if f.Inline {
+			disposition = "inline"
+		}
+		value := mime.FormatMediaType(disposition, map[string]string{"filename": path.Base(f.Name)})
+		if value != "" {
+			header.Set("Content-Disposition", value)
+		}
+	}
+
+	http.ServeContent(w, r, f.Name, f.ModTime, f.Content)
+}
+
+// asFile returns the File held by v, which is usually a pointer to a handler response
+func asFile(v interface{}) (*File, bool) {
+	switch f := v.(type) {
+	case File:
+		return &f, true
+	case *File:
+		return f, f != nil
+	case **File:
+		return asFile(*f)
+	}
+	return nil, false
+}
+
+// quoteETag turns an opaque version string into a strong entity tag. Values
+// that are already quoted, or weak, are returned unchanged.
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
@@ -0,0 +1,115 @@
+package gohandlr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func fileHandler(modTime time.Time) http.HandlerFunc {
+	return HandlerNoRequestWithResponse(func(ctx context.Context) (File, error) {
+		file := NewFile(strings.NewReader("hello world"), "hello.txt", "text/plain")
+		file.ModTime = modTime
+		file.ETag = "v1"
+		return file, nil
+	})
+}
+
+func TestFileResponse(t *testing.T) {
+	rec := httptest.NewRecorder()
+	fileHandler(time.Time{})(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusOK, rec.Code)
+	}
+	if rec.Body.String() != "hello world" {
+		t.Errorf("Expected body: %q, got: %q", "hello world", rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Expected content type: %s, got: %s", "text/plain", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename=hello.txt` {
+		t.Errorf("Expected content disposition: %s, got: %s", `attachment; filename=hello.txt`, got)
+	}
+	if got := rec.Header().Get("ETag"); got != `"v1"` {
+		t.Errorf("Expected etag: %s, got: %s", `"v1"`, got)
+	}
+}
+
+func TestFileResponseRange(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=6-")
+	rec := httptest.NewRecorder()
+	fileHandler(time.Time{})(rec, req)
+
+	if rec.Code != http.StatusPartialContent {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusPartialContent, rec.Code)
+	}
+	if rec.Body.String() != "world" {
+		t.Errorf("Expected body: %q, got: %q", "world", rec.Body.String())
+	}
+
+	// A stale If-Range falls back to the full content
+	req.Header.Set("If-Range", `"v0"`)
+	rec = httptest.NewRecorder()
+	fileHandler(time.Time{})(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestFileResponseConditional(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"v1"`)
+	rec := httptest.NewRecorder()
+	fileHandler(time.Time{})(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusNotModified, rec.Code)
+	}
+
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-Modified-Since", modTime.Add(time.Hour).Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	HandlerNoRequestWithResponse(func(ctx context.Context) (File, error) {
+		return File{Content: strings.NewReader("hello"), ModTime: modTime}, nil
+	})(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusNotModified, rec.Code)
+	}
+}
+
+func TestFileFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"docs/report.csv": &fstest.MapFile{Data: []byte("a,b\n1,2\n"), ModTime: time.Now()},
+	}
+
+	file, err := FileFromFS(fsys, "docs/report.csv")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if file.Name != "report.csv" {
+		t.Errorf("Expected name: %s, got: %s", "report.csv", file.Name)
+	}
+
+	rec := httptest.NewRecorder()
+	file.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/csv") {
+		t.Errorf("Expected content type: %s, got: %s", "text/csv", got)
+	}
+	if rec.Body.String() != "a,b\n1,2\n" {
+		t.Errorf("Expected body: %q, got: %q", "a,b\n1,2\n", rec.Body.String())
+	}
+
+	if _, err := FileFromFS(fsys, "docs"); err == nil {
+		t.Errorf("Expected an error when serving a directory")
+	}
+}
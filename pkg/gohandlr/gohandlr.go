@@ -2,11 +2,17 @@ package gohandlr
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"reflect"
+	"slices"
 	"strings"
 )
 
@@ -16,6 +22,34 @@ type Config struct {
 	Marshaler       map[string]Marshaler
 	Validate        Validator
 	ParameterReader ParameterReader
+	// ETag hashes marshaled responses into an ETag header
+	ETag bool
+	// CurrentETag looks up the entity tag of the resource an unsafe request
+	// changes, which its If-Match is checked against, when set
+	CurrentETag func(ctx context.Context, req any) (string, error)
+	// Encoders are the content codings for request and response bodies, in order of preference
+	Encoders []Encoder
+	// Compression compresses marshaled responses when set
+	Compression *Compression
+	// MaxBodySize limits request bodies, after decompression, when positive
+	MaxBodySize int64
+	// Idempotency replays responses of unsafe requests with a repeated Idempotency-Key
+	Idempotency *Idempotency
+	// ResponseHeaders are the headers the responses of the operation
+	// document, which replays repeat along with the representation headers
+	ResponseHeaders []string
+	// RateLimit throttles requests when set
+	RateLimit *RateLimit
+	// Security authenticates requests before they are read
+	Security Security
+	// CORS allows cross-origin requests when set
+	CORS *CORS
+	// OpenAPIValidator validates requests against an OpenAPI document when set
+	OpenAPIValidator *OpenAPIValidator
+	// Version is published in the API-Version header when set
+	Version string
+	// Docs makes generated packages serve their OpenAPI document
+	Docs bool
 }
 
 func (c *Config) ReadParameter(r *http.Request, v interface{}) error {
@@ -38,6 +72,68 @@ func (c *Config) Unmarshal(r *http.Request, v interface{}) error {
 }
 
 func (c *Config) Marshal(r *http.Request, w http.ResponseWriter, v interface{}) error {
+	if resp, ok := asStatusResponse(v); ok {
+		return c.writeStatusResponse(r, w, resp)
+	}
+
+	// Files are streamed as-is rather than marshaled
+	if f, ok := asFile(v); ok {
+		f.ServeHTTP(w, r)
+		return nil
+	}
+
+	var etag string
+	if e, ok := v.(enveloped); ok {
+		v, etag = e.envelope()
+	}
+
+	if !c.ETag && etag == "" && c.Compression == nil {
+		return c.marshal(r, w, v)
+	}
+
+	// Buffer the response so it can be hashed and compressed
+	buf := &bufferedWriter{w: w}
+	if err := c.marshal(r, buf, v); err != nil {
+		return err
+	}
+
+	var encoder Encoder
+	if c.Compression != nil {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoder = c.negotiateEncoding(r, w.Header().Get("Content-Type"), buf.buf.Len())
+	}
+
+	if c.ETag || etag != "" {
+		if etag == "" {
+			etag = hashETag(buf.buf.Bytes())
+		}
+		// Each encoding is a different representation with its own tag
+		if encoder != nil {
+			etag = codingETag(etag, encoder.Encoding())
+		}
+		w.Header().Set("ETag", etag)
+
+		if (r.Method == http.MethodGet || r.Method == http.MethodHead) && etagMatch(r.Header.Get("If-None-Match"), etag, true) {
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
+	if encoder == nil {
+		return buf.flush()
+	}
+
+	w.Header().Set("Content-Encoding", encoder.Encoding())
+	w.Header().Del("Content-Length")
+	if buf.status != 0 {
+		w.WriteHeader(buf.status)
+	}
+	return encoder.Encode(w, buf.buf.Bytes())
+}
+
+// marshal writes v with the first marshaler that matches the Accept header
+func (c *Config) marshal(r *http.Request, w http.ResponseWriter, v interface{}) error {
 	if c.Marshaler == nil {
 		return nil
 	}
@@ -98,7 +194,13 @@ func DefaultUnMarshalJSON(r *http.Request, v interface{}) error {
 	}
 	defer r.Body.Close()
 
-	bodyBytes = append(bodyBytes)
+	// Inputs with a Body field get the body there only, so that a property
+	// of the body can't overwrite a parameter of the same name
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.Elem().Kind() == reflect.Struct {
+		if body := rv.Elem().FieldByName("Body"); body.IsValid() && body.CanSet() {
+			return json.Unmarshal(bodyBytes, body.Addr().Interface())
+		}
+	}
 
 	// Create a new JSON decoder for the request body
 	dec := json.NewDecoder(bytes.NewReader(bodyBytes))
@@ -187,6 +289,51 @@ var DefaultConfig = Config{
 	Marshaler: map[string]Marshaler{
 		"application/json": DefaultMarshalJSON,
 	},
+	Encoders: []Encoder{
+		GzipEncoder(gzip.DefaultCompression),
+		DeflateEncoder(zlib.DefaultCompression),
+	},
+}
+
+// wrap applies the request handling shared by every handler around handler
+func (c *Config) wrap(handler http.HandlerFunc) http.HandlerFunc {
+	next := handler
+	handler = func(w http.ResponseWriter, r *http.Request) {
+		next(w, c.withPreconditions(r))
+	}
+
+	if c.OpenAPIValidator != nil {
+		handler = c.OpenAPIValidator.middleware(handler, c)
+	}
+	if c.Idempotency != nil {
+		handler = c.Idempotency.middleware(handler, c)
+	}
+	if c.RateLimit != nil {
+		handler = c.RateLimit.middleware(handler)
+	}
+	// Authenticate before doing any other work
+	if len(c.Security.Requirements) > 0 {
+		handler = c.Security.middleware(handler)
+	}
+	// Browsers can only read errors, like a failed authentication, with CORS headers
+	if c.CORS != nil {
+		handler = c.CORS.middleware(handler)
+	}
+	if c.Version != "" {
+		next := handler
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			c.setVersion(w)
+			next(w, r)
+		}
+	}
+	return handler
+}
+
+// setVersion publishes the version of the API, if any
+func (c *Config) setVersion(w http.ResponseWriter) {
+	if c.Version != "" {
+		w.Header().Set("API-Version", c.Version)
+	}
 }
 
 func readRequest(r *http.Request, config *Config, v interface{}) error {
@@ -195,8 +342,17 @@ func readRequest(r *http.Request, config *Config, v interface{}) error {
 		return fmt.Errorf("failed to read parameters: %w", err)
 	}
 
+	// Decompress and limit the request body
+	if err := config.decodeBody(r); err != nil {
+		return err
+	}
+
 	// Read the request body
 	if err := config.Unmarshal(r, v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return ErrorRequestEntityTooLarge(fmt.Errorf("failed to unmarshal body: %w", err))
+		}
 		return fmt.Errorf("failed to unmarshal body: %w", err)
 	}
 
@@ -208,66 +364,88 @@ func readRequest(r *http.Request, config *Config, v interface{}) error {
 	return nil
 }
 
-func HandlerNoRequestNoResponse(process func(context.Context) error) func(w http.ResponseWriter, r *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
+func HandlerNoRequestNoResponse(process func(context.Context) error, options ...Option) func(w http.ResponseWriter, r *http.Request) {
+	config := NewConfig(options...)
+	return config.wrap(func(w http.ResponseWriter, r *http.Request) {
+		if err := config.checkCurrentETag(r.Context(), nil); err != nil {
+			writeError(w, err, http.StatusPreconditionFailed)
+			return
+		}
+
 		// Process the request
 		err := process(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err, http.StatusInternalServerError)
 			return
 		}
 
 		w.WriteHeader(http.StatusNoContent)
-	}
+	})
 }
 
 func HandlerWithRequestNoResponse[Request any](process func(context.Context, Request) error, options ...Option) func(w http.ResponseWriter, r *http.Request) {
 	config := NewConfig(options...)
-	return func(w http.ResponseWriter, r *http.Request) {
+	return config.wrap(func(w http.ResponseWriter, r *http.Request) {
 		var req Request
 		var err error
 
 		// Read the request
 		err = readRequest(r, config, &req)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		// Check If-Match before anything is changed
+		err = config.checkCurrentETag(r.Context(), req)
+		if err != nil {
+			writeError(w, err, http.StatusPreconditionFailed)
 			return
 		}
 
 		// Process the request
 		err = process(r.Context(), req)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err, http.StatusInternalServerError)
 			return
 		}
 
 		w.WriteHeader(http.StatusNoContent)
-	}
+	})
 }
 
 func HandlerNoRequestWithResponse[Response any](process func(context.Context) (Response, error), options ...Option) func(w http.ResponseWriter, r *http.Request) {
 	config := NewConfig(options...)
-	return func(w http.ResponseWriter, r *http.Request) {
+	return config.wrap(func(w http.ResponseWriter, r *http.Request) {
 		var err error
 
+		if err = config.checkCurrentETag(r.Context(), nil); err != nil {
+			writeError(w, err, http.StatusPreconditionFailed)
+			return
+		}
+
 		// Process the request
 		resp, err := process(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err, http.StatusInternalServerError)
 			return
 		}
 
 		// Write the response
 		err = config.Marshal(r, w, &resp)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err, http.StatusInternalServerError)
 			return
 		}
-	}
+	})
 }
 
 func NewConfig(options ...Option) *Config {
 	config := DefaultConfig
+	// Options add to these, so they must not be shared with DefaultConfig
+	config.UnMarshaler = maps.Clone(config.UnMarshaler)
+	config.Marshaler = maps.Clone(config.Marshaler)
+	config.Encoders = slices.Clone(config.Encoders)
 	for _, option := range options {
 		option(&config)
 	}
@@ -276,29 +454,36 @@ func NewConfig(options ...Option) *Config {
 
 func HandlerWithRequestWithResponse[Request, Response any](process func(context.Context, Request) (Response, error), options ...Option) http.HandlerFunc {
 	config := NewConfig(options...)
-	return func(w http.ResponseWriter, r *http.Request) {
+	return config.wrap(func(w http.ResponseWriter, r *http.Request) {
 		var req Request
 		var err error
 
 		// Read the request
 		err = readRequest(r, config, &req)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		// Check If-Match before anything is changed
+		err = config.checkCurrentETag(r.Context(), req)
+		if err != nil {
+			writeError(w, err, http.StatusPreconditionFailed)
 			return
 		}
 
 		// Process the request
 		resp, err := process(r.Context(), req)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err, http.StatusInternalServerError)
 			return
 		}
 
 		// Write the response
 		err = config.Marshal(r, w, &resp)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err, http.StatusInternalServerError)
 			return
 		}
-	}
+	})
 }
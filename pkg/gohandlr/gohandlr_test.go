@@ -0,0 +1,52 @@
+package gohandlr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type itemInput struct {
+	ID   int `json:"id" path:"id"`
+	Body struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+}
+
+func TestDefaultUnMarshalJSONBody(t *testing.T) {
+	var got itemInput
+	handler := HandlerWithRequestNoResponse(func(ctx context.Context, req itemInput) error {
+		got = req
+		return nil
+	}, WithParamsReader(func(r *http.Request, v interface{}) error {
+		return ReadPathParam("7", Param{Name: "id", In: "path", Style: "simple", Required: true}, &v.(*itemInput).ID)
+	}))
+
+	// A property of the body doesn't overwrite the parameter of the same
+	// name, even when it is missing or zero
+	tests := []struct {
+		body   string
+		bodyID int
+	}{
+		{`{"id":8,"name":"eight"}`, 8},
+		{`{"id":0,"name":"eight"}`, 0},
+		{`{"name":"eight"}`, 0},
+	}
+	for _, test := range tests {
+		got = itemInput{}
+		req := httptest.NewRequest(http.MethodPut, "/items/7", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("%s: expected status code: %d, got: %d", test.body, http.StatusNoContent, rec.Code)
+		}
+		if got.ID != 7 || got.Body.Name != "eight" || got.Body.ID != test.bodyID {
+			t.Errorf("%s: expected id 7 and the body, got: %+v", test.body, got)
+		}
+	}
+}
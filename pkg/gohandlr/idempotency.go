@@ -0,0 +1,449 @@
+package gohandlr
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord is the state of a request made with an Idempotency-Key
+type IdempotencyRecord struct {
+	// Fingerprint identifies the principal, method, path and body of the request
+	Fingerprint string
+	// Completed is false while the first request is still being processed
+	Completed bool
+	Status    int
+	Header    http.Header
+	// Body is stored without a content coding, so a replay can be encoded
+	// for the Accept-Encoding of the retry
+	Body    []byte
+	Expires time.Time
+}
+
+// IdempotencyStore keeps the responses of requests made with an Idempotency-Key
+type IdempotencyStore interface {
+	// Reserve stores record for key unless an unexpired record exists. It
+	// returns the existing record and false, or record and true.
+	Reserve(ctx context.Context, key string, record IdempotencyRecord) (IdempotencyRecord, bool, error)
+	// Complete replaces the record for key with the finished response
+	Complete(ctx context.Context, key string, record IdempotencyRecord) error
+	// Release deletes the record for key so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// Idempotency configures how requests with an Idempotency-Key are replayed
+type Idempotency struct {
+	Store IdempotencyStore
+	// TTL is how long a response is replayed for
+	TTL time.Duration
+}
+
+// WithIdempotency stores the first response to each Idempotency-Key of unsafe
+// requests and replays it for retries within ttl. A retry while the first
+// request is still processing gets 409 Conflict, and a key reused with a
+// different request gets 422 Unprocessable Entity. Keys are scoped to the
+// principal of the request, so clients can't replay each other's responses.
+// The principal needs a stable identity for that, see PrincipalID: keys of
+// principals without one aren't scoped at all. A replay repeats the
+// status, body and representation headers of the response, while headers
+// like RateLimit-Remaining are those of the retry.
+func WithIdempotency(store IdempotencyStore, ttl time.Duration) Option {
+	return func(c *Config) {
+		c.Idempotency = &Idempotency{Store: store, TTL: ttl}
+	}
+}
+
+// WithResponseHeaders names the headers the responses of an operation
+// document, which replays repeat. Generated handlers set it.
+func WithResponseHeaders(names ...string) Option {
+	return func(c *Config) {
+		c.ResponseHeaders = names
+	}
+}
+
+// representationHeaders are the headers of a stored response that replays
+// repeat besides the documented ones
+var representationHeaders = []string{"Content-Disposition", "Content-Type", "ETag", "Location"}
+
+func (i *Idempotency) middleware(next http.HandlerFunc, c *Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || isSafeMethod(r.Method) {
+			next(w, r)
+			return
+		}
+
+		// Read the body to fingerprint it, then put it back for the handler
+		var body []byte
+		if r.Body != nil {
+			reader := io.Reader(r.Body)
+			if c.MaxBodySize > 0 {
+				reader = http.MaxBytesReader(w, r.Body, c.MaxBodySize)
+			}
+			var err error
+			body, err = io.ReadAll(reader)
+			r.Body.Close()
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					err = ErrorRequestEntityTooLarge(err)
+				}
+				writeError(w, err, http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		scope := idempotencyScope(r)
+		key = scopedKey(scope, key)
+		record := IdempotencyRecord{
+			Fingerprint: fingerprint(r, scope, body),
+			Expires:     time.Now().Add(i.TTL),
+		}
+		existing, reserved, err := i.Store.Reserve(r.Context(), key, record)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if !reserved {
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				writeError(w, ErrorUnprocessableEntity(errors.New("idempotency key was used for a different request")), http.StatusUnprocessableEntity)
+			case !existing.Completed:
+				writeError(w, ErrorConflict(errors.New("a request with this idempotency key is being processed")), http.StatusConflict)
+			default:
+				c.replay(w, r, existing)
+			}
+			return
+		}
+
+		rec := &recordingWriter{ResponseWriter: w}
+		completed := false
+		defer func() {
+			// Let the client retry when the request failed or panicked
+			if !completed {
+				i.Store.Release(context.WithoutCancel(r.Context()), key)
+			}
+		}()
+
+		next(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= http.StatusInternalServerError {
+			return
+		}
+
+		record.Completed = true
+		record.Status = status
+		record.Header = w.Header().Clone()
+		record.Body = rec.body.Bytes()
+		if err := c.decodeRecord(&record); err != nil {
+			return
+		}
+		record.Header = c.replayedHeader(record.Header)
+		if err := i.Store.Complete(context.WithoutCancel(r.Context()), key, record); err == nil {
+			completed = true
+		}
+	}
+}
+
+// idempotencyScope identifies who made a request: the identity of its
+// principal, or the hash of its credentials when it wasn't authenticated by a
+// scheme. Requests without either, and those of principals without a stable
+// identity, share the unscoped keys.
+func idempotencyScope(r *http.Request) string {
+	if p := Principal(r.Context()); p != nil {
+		if id := PrincipalID(r.Context()); id != "" {
+			return "principal:" + id
+		}
+		return ""
+	}
+	for _, name := range []string{"Authorization", "X-API-Key"} {
+		if credential := r.Header.Get(name); credential != "" {
+			sum := sha256.Sum256([]byte(credential))
+			return strings.ToLower(name) + ":" + hex.EncodeToString(sum[:])
+		}
+	}
+	return ""
+}
+
+// scopedKey is the store key of an Idempotency-Key within a scope
+func scopedKey(scope, key string) string {
+	if scope == "" {
+		return key
+	}
+	sum := sha256.Sum256([]byte(scope))
+	return hex.EncodeToString(sum[:]) + ":" + key
+}
+
+// fingerprint hashes the parts of a request that must match for a replay
+func fingerprint(r *http.Request, scope string, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, scope+"\n")
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// decodeRecord removes the content coding of a recorded response, along
+// with the coding suffix of its ETag
+func (c *Config) decodeRecord(record *IdempotencyRecord) error {
+	encoding := record.Header.Get("Content-Encoding")
+	if encoding == "" {
+		return nil
+	}
+	encoder := c.encoder(encoding)
+	if encoder == nil {
+		return fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	body, err := encoder.Decode(bytes.NewReader(record.Body))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if record.Body, err = io.ReadAll(body); err != nil {
+		return err
+	}
+
+	record.Header.Del("Content-Encoding")
+	record.Header.Del("Content-Length")
+	if etag := record.Header.Get("ETag"); etag != "" {
+		record.Header.Set("ETag", withoutCoding(etag, encoding))
+	}
+	return nil
+}
+
+// replayedHeader keeps the headers of a response that replays repeat. The
+// others, set by middleware like rate limiting and CORS, are left to the
+// retry.
+func (c *Config) replayedHeader(header http.Header) http.Header {
+	replayed := http.Header{}
+	for _, names := range [][]string{representationHeaders, c.ResponseHeaders} {
+		for _, name := range names {
+			if values := header.Values(name); len(values) > 0 {
+				replayed[http.CanonicalHeaderKey(name)] = slices.Clone(values)
+			}
+		}
+	}
+	return replayed
+}
+
+// replay writes a stored response, encoded for the Accept-Encoding of r,
+// over the headers middleware set for r
+func (c *Config) replay(w http.ResponseWriter, r *http.Request, record IdempotencyRecord) {
+	header := w.Header()
+	for name, values := range c.replayedHeader(record.Header) {
+		header[name] = values
+	}
+	header.Set("Idempotent-Replayed", "true")
+
+	body := record.Body
+	if header.Get("Content-Encoding") == "" {
+		if encoder := c.negotiateEncoding(r, header.Get("Content-Type"), len(body)); encoder != nil {
+			var buf bytes.Buffer
+			if err := encoder.Encode(&buf, body); err == nil {
+				body = buf.Bytes()
+				header.Set("Content-Encoding", encoder.Encoding())
+				header.Del("Content-Length")
+				if etag := header.Get("ETag"); etag != "" {
+					header.Set("ETag", codingETag(etag, encoder.Encoding()))
+				}
+			}
+		}
+	}
+	w.WriteHeader(record.Status)
+	w.Write(body)
+}
+
+// recordingWriter writes a response through while keeping a copy of it
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(p []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	rw.body.Write(p)
+	return rw.ResponseWriter.Write(p)
+}
+
+// memorySweepInterval is how often a MemoryIdempotencyStore drops expired records
+const memorySweepInterval = time.Minute
+
+// MemoryIdempotencyStore is an IdempotencyStore that keeps records in memory.
+// An expired record is replaced when its key is reserved again, and the
+// others are dropped at most once every minute.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]IdempotencyRecord
+	nextSweep time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records:   make(map[string]IdempotencyRecord),
+		nextSweep: time.Now().Add(memorySweepInterval),
+	}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, key string, record IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := s.records[key]; ok && now.Before(existing.Expires) {
+		return existing, false, nil
+	}
+
+	if !now.Before(s.nextSweep) {
+		for k, r := range s.records {
+			if !now.Before(r.Expires) {
+				delete(s.records, k)
+			}
+		}
+		s.nextSweep = now.Add(memorySweepInterval)
+	}
+
+	s.records[key] = record
+	return record, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key string, record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = record
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// FileIdempotencyStore is an IdempotencyStore that keeps one JSON file per key
+// in a directory, so records survive restarts and can be shared by processes
+// on the same host.
+type FileIdempotencyStore struct {
+	dir string
+}
+
+// NewFileIdempotencyStore creates a FileIdempotencyStore in dir, creating it if needed
+func NewFileIdempotencyStore(dir string) (*FileIdempotencyStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileIdempotencyStore{dir: dir}, nil
+}
+
+func (s *FileIdempotencyStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (s *FileIdempotencyStore) Reserve(ctx context.Context, key string, record IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	path := s.path(key)
+	tmp, err := s.writeTemp(record)
+	if err != nil {
+		return IdempotencyRecord{}, false, err
+	}
+	defer os.Remove(tmp)
+
+	// Retry once after removing an expired record
+	for attempt := 0; attempt < 2; attempt++ {
+		// Linking fails when the record exists, which makes the reservation atomic
+		err = os.Link(tmp, path)
+		if err == nil {
+			return record, true, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return IdempotencyRecord{}, false, err
+		}
+
+		existing, err := s.read(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return IdempotencyRecord{}, false, err
+		}
+		if time.Now().Before(existing.Expires) {
+			return existing, false, nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return IdempotencyRecord{}, false, err
+		}
+	}
+	return IdempotencyRecord{}, false, ErrorConflict(errors.New("failed to reserve idempotency key"))
+}
+
+func (s *FileIdempotencyStore) Complete(ctx context.Context, key string, record IdempotencyRecord) error {
+	tmp, err := s.writeTemp(record)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path(key)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (s *FileIdempotencyStore) Release(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileIdempotencyStore) writeTemp(record IdempotencyRecord) (string, error) {
+	f, err := os.CreateTemp(s.dir, "record-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(record); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func (s *FileIdempotencyStore) read(path string) (IdempotencyRecord, error) {
+	var record IdempotencyRecord
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return record, err
+	}
+	err = json.Unmarshal(data, &record)
+	return record, err
+}
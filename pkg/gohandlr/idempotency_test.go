@@ -0,0 +1,276 @@
+package gohandlr
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type idempotencyBody struct {
+	Body struct {
+		Name string `json:"name"`
+	}
+}
+
+func idempotentRequest(key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	return req
+}
+
+func testIdempotencyStore(t *testing.T, store IdempotencyStore) {
+	var calls atomic.Int32
+	handler := HandlerWithRequestWithResponse(func(ctx context.Context, req idempotencyBody) (etagUser, error) {
+		calls.Add(1)
+		return etagUser{Name: req.Body.Name}, nil
+	}, WithIdempotency(store, time.Minute))
+
+	rec := httptest.NewRecorder()
+	handler(rec, idempotentRequest("key-1", `{"name":"gopher"}`))
+	first := rec.Body.String()
+
+	rec = httptest.NewRecorder()
+	handler(rec, idempotentRequest("key-1", `{"name":"gopher"}`))
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected process to be called once, got: %d", calls.Load())
+	}
+	if rec.Body.String() != first {
+		t.Errorf("Expected replayed body: %q, got: %q", first, rec.Body.String())
+	}
+	if rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected the Idempotent-Replayed header")
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, idempotentRequest("key-1", `{"name":"other"}`))
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, idempotentRequest("key-2", `{"name":"gopher"}`))
+
+	if calls.Load() != 2 {
+		t.Errorf("Expected process to be called twice, got: %d", calls.Load())
+	}
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	testIdempotencyStore(t, NewMemoryIdempotencyStore())
+}
+
+func TestFileIdempotencyStore(t *testing.T) {
+	store, err := NewFileIdempotencyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	testIdempotencyStore(t, store)
+}
+
+func TestIdempotencyConcurrent(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := HandlerWithRequestNoResponse(func(ctx context.Context, req idempotencyBody) error {
+		close(started)
+		<-release
+		return nil
+	}, WithIdempotency(NewMemoryIdempotencyStore(), time.Minute))
+
+	done := make(chan struct{})
+	go func() {
+		handler(httptest.NewRecorder(), idempotentRequest("key", `{"name":"gopher"}`))
+		close(done)
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler(rec, idempotentRequest("key", `{"name":"gopher"}`))
+	close(release)
+	<-done
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusConflict, rec.Code)
+	}
+}
+
+func TestIdempotencyReleasesFailures(t *testing.T) {
+	var calls atomic.Int32
+	handler := HandlerWithRequestNoResponse(func(ctx context.Context, req idempotencyBody) error {
+		calls.Add(1)
+		return ErrorUnavailable(context.DeadlineExceeded)
+	}, WithIdempotency(NewMemoryIdempotencyStore(), time.Minute))
+
+	handler(httptest.NewRecorder(), idempotentRequest("key", `{}`))
+	handler(httptest.NewRecorder(), idempotentRequest("key", `{}`))
+
+	if calls.Load() != 2 {
+		t.Errorf("Expected failed requests to be retried, got %d calls", calls.Load())
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	var calls atomic.Int32
+	handler := HandlerWithRequestWithResponse(func(ctx context.Context, req idempotencyBody) (etagUser, error) {
+		calls.Add(1)
+		return etagUser{Name: req.Body.Name}, nil
+	}, WithIdempotency(NewMemoryIdempotencyStore(), time.Minute))
+
+	// The same key and body from other credentials isn't a retry
+	for _, credential := range []string{"Bearer alice", "Bearer bob", "Bearer alice"} {
+		req := idempotentRequest("key", `{"name":"gopher"}`)
+		req.Header.Set("Authorization", credential)
+		handler(httptest.NewRecorder(), req)
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("Expected process to be called twice, got: %d", calls.Load())
+	}
+}
+
+type account struct {
+	id string
+}
+
+func (a *account) PrincipalID() string { return a.id }
+
+func TestIdempotencyPrincipalScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal any
+		scope     string
+	}{
+		{"string", "alice", "principal:alice"},
+		{"identified", &account{id: "42"}, "principal:42"},
+		{"unidentified", &struct{ Name string }{"alice"}, ""},
+	}
+
+	for _, test := range tests {
+		req := idempotentRequest("key", `{}`)
+		req.Header.Set("Authorization", "Bearer alice")
+		ctx := context.WithValue(req.Context(), authenticationKey{}, authentication{principal: test.principal})
+		if scope := idempotencyScope(req.WithContext(ctx)); scope != test.scope {
+			t.Errorf("%s: expected scope: %q, got: %q", test.name, test.scope, scope)
+		}
+	}
+}
+
+func TestIdempotencyReplayHeaders(t *testing.T) {
+	handler := jobHandler(jobAccepted{RetryAfter: 30}, WithIdempotency(NewMemoryIdempotencyStore(), time.Minute), WithResponseHeaders("Retry-After"))
+
+	// The documented headers are replayed, and those middleware sets are
+	// the retry's own
+	for _, remaining := range []string{"9", "8"} {
+		rec := httptest.NewRecorder()
+		rec.Header().Set("RateLimit-Remaining", remaining)
+		handler(rec, idempotentRequest("key", `{}`))
+
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("Expected status code: %d, got: %d", http.StatusAccepted, rec.Code)
+		}
+		if retryAfter := rec.Header().Get("Retry-After"); retryAfter != "30" {
+			t.Errorf("Expected Retry-After: 30, got: %q", retryAfter)
+		}
+		if actual := rec.Header().Get("RateLimit-Remaining"); actual != remaining {
+			t.Errorf("Expected RateLimit-Remaining: %s, got: %q", remaining, actual)
+		}
+	}
+}
+
+func TestIdempotencyReplayEncoding(t *testing.T) {
+	name := strings.Repeat("gopher", 500)
+	handler := HandlerWithRequestWithResponse(func(ctx context.Context, req idempotencyBody) (etagUser, error) {
+		return etagUser{Name: name}, nil
+	}, WithIdempotency(NewMemoryIdempotencyStore(), time.Minute), WithCompression(DefaultCompression), WithETag())
+
+	req := idempotentRequest("key", `{}`)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Expected content encoding: %s, got: %s", "gzip", got)
+	}
+	etag := withoutCoding(rec.Header().Get("ETag"), "gzip")
+
+	// A retry that doesn't accept gzip gets the response without it
+	tests := []struct {
+		acceptEncoding string
+		encoding       string
+	}{
+		{"", ""},
+		{"deflate", "deflate"},
+		{"gzip", "gzip"},
+	}
+	for _, test := range tests {
+		req := idempotentRequest("key", `{}`)
+		req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if rec.Header().Get("Idempotent-Replayed") != "true" {
+			t.Errorf("Accept-Encoding %q: expected the Idempotent-Replayed header", test.acceptEncoding)
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != test.encoding {
+			t.Errorf("Accept-Encoding %q: expected content encoding: %q, got: %q", test.acceptEncoding, test.encoding, got)
+		}
+		wantETag := etag
+		if test.encoding != "" {
+			wantETag = codingETag(etag, test.encoding)
+		}
+		if got := rec.Header().Get("ETag"); got != wantETag {
+			t.Errorf("Accept-Encoding %q: expected etag: %s, got: %s", test.acceptEncoding, wantETag, got)
+		}
+
+		body := io.Reader(rec.Body)
+		if test.encoding != "" {
+			decoded, err := DefaultConfig.encoder(test.encoding).Decode(rec.Body)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			body = decoded
+		}
+		content, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := `{"name":"` + name + "\"}\n"; string(content) != want {
+			t.Errorf("Accept-Encoding %q: expected body of %d bytes, got: %d bytes", test.acceptEncoding, len(want), len(content))
+		}
+	}
+}
+
+func TestMemoryIdempotencyStoreExpiry(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	ctx := context.Background()
+
+	expired := IdempotencyRecord{Fingerprint: "old", Expires: time.Now().Add(-time.Second)}
+	if _, reserved, _ := store.Reserve(ctx, "key", expired); !reserved {
+		t.Fatalf("Expected the first reservation to succeed")
+	}
+
+	// An expired record is replaced rather than replayed
+	record := IdempotencyRecord{Fingerprint: "new", Expires: time.Now().Add(time.Minute)}
+	got, reserved, err := store.Reserve(ctx, "key", record)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reserved || got.Fingerprint != "new" {
+		t.Errorf("Expected the expired record to be replaced, got: %+v", got)
+	}
+
+	// Other expired records are dropped by the next sweep
+	store.Reserve(ctx, "other", expired)
+	store.nextSweep = time.Now()
+	store.Reserve(ctx, "key-2", record)
+	if _, ok := store.records["other"]; ok {
+		t.Errorf("Expected the sweep to drop the expired record")
+	}
+}
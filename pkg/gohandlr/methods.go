@@ -0,0 +1,79 @@
+package gohandlr
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MethodNotAllowedHandler answers requests with a method the path doesn't
+// define with 405 Method Not Allowed and the methods it does define
+func MethodNotAllowedHandler(methods []string, options ...Option) http.HandlerFunc {
+	config := NewConfig(options...)
+	allow := strings.Join(allowedMethods(methods), ", ")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		config.setVersion(w)
+		w.Header().Set("Allow", allow)
+		if config.CORS != nil {
+			config.CORS.setOrigin(w, r)
+		}
+		writeError(w, ErrorMethodNotAllowed(fmt.Errorf("method %s is not allowed", r.Method)), http.StatusMethodNotAllowed)
+	}
+}
+
+// HeadHandler answers HEAD requests with the headers get writes for the same
+// GET request, without the body
+func HeadHandler(get http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := r.Clone(r.Context())
+		req.Method = http.MethodGet
+
+		hw := &headWriter{w: w}
+		get.ServeHTTP(hw, req)
+		hw.finish()
+	}
+}
+
+// headWriter discards the body of a response, counting its length
+type headWriter struct {
+	w      http.ResponseWriter
+	status int
+	length int
+}
+
+func (h *headWriter) Header() http.Header {
+	return h.w.Header()
+}
+
+func (h *headWriter) WriteHeader(status int) {
+	if h.status == 0 {
+		h.status = status
+	}
+}
+
+func (h *headWriter) Write(p []byte) (int, error) {
+	if h.status == 0 {
+		h.status = http.StatusOK
+	}
+	h.length += len(p)
+	return len(p), nil
+}
+
+// finish writes the status with the length of the discarded body
+func (h *headWriter) finish() {
+	if h.status == 0 {
+		h.status = http.StatusOK
+	}
+	if h.status == http.StatusOK && h.w.Header().Get("Content-Length") == "" {
+		h.w.Header().Set("Content-Length", strconv.Itoa(h.length))
+	}
+	h.w.WriteHeader(h.status)
+}
+
+// Pattern turns the method, path and handler a generated Handle function
+// returns into the arguments of http.ServeMux.HandleFunc
+func Pattern(method, path string, handler http.HandlerFunc) (string, http.HandlerFunc) {
+	return method + " " + path, handler
+}
@@ -0,0 +1,61 @@
+package gohandlr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMethodNotAllowedHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	MethodNotAllowedHandler([]string{http.MethodGet, http.MethodPut})(rec, httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET, PUT, HEAD, OPTIONS" {
+		t.Errorf("Expected allow: %s, got: %s", "GET, PUT, HEAD, OPTIONS", got)
+	}
+}
+
+func TestOptionsHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	OptionsHandler([]string{http.MethodPost})(rec, httptest.NewRequest(http.MethodOptions, "/users", nil))
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusNoContent, rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "POST, OPTIONS" {
+		t.Errorf("Expected allow: %s, got: %s", "POST, OPTIONS", got)
+	}
+}
+
+func TestHeadHandler(t *testing.T) {
+	var method string
+	get := HandlerNoRequestWithResponse(func(ctx context.Context) (etagUser, error) {
+		return etagUser{Name: "gopher"}, nil
+	}, WithETag())
+
+	rec := httptest.NewRecorder()
+	HeadHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		get(w, r)
+	}))(rec, httptest.NewRequest(http.MethodHead, "/users/1", nil))
+
+	if method != http.MethodGet {
+		t.Errorf("Expected the GET handler to see method: %s, got: %s", http.MethodGet, method)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusOK, rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("Expected an empty body, got: %q", rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Length"); got != "18" {
+		t.Errorf("Expected content length: %s, got: %s", "18", got)
+	}
+	if rec.Header().Get("ETag") == "" {
+		t.Errorf("Expected the headers of the GET response")
+	}
+}
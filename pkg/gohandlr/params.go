@@ -0,0 +1,526 @@
+package gohandlr
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Param describes a parameter of an OpenAPI operation and how its value is
+// serialized, like
+//
+//	Param{Name: "id", In: "query", Style: "form", Explode: true}
+type Param struct {
+	Name string
+	// In is path, query, header or cookie
+	In string
+	// Style is simple, label or matrix for path parameters, form,
+	// spaceDelimited, pipeDelimited or deepObject for query parameters,
+	// simple for headers and form for cookies
+	Style   string
+	Explode bool
+	// Required parameters that are missing are a 400 Bad Request
+	Required bool
+	// Default is the JSON of the value of a missing parameter, if any
+	Default string
+}
+
+// ReadParam reads the query, header or cookie parameter p of r into v,
+// which points to a scalar, a slice or a struct or map for objects. Missing
+// and invalid values are a 400 Bad Request naming the parameter.
+func ReadParam(r *http.Request, p Param, v any) error {
+	var values paramValues
+	switch p.In {
+	case "query":
+		values = p.queryValues(r, v)
+	case "header":
+		if header := r.Header.Values(p.Name); len(header) > 0 {
+			values = p.simpleValues(strings.Join(header, ","))
+		}
+	case "cookie":
+		if cookie, err := r.Cookie(p.Name); err == nil && cookie.Value != "" {
+			values = delimitedValues(cookie.Value, ",")
+		}
+	default:
+		return fmt.Errorf("unsupported parameter location %q", p.In)
+	}
+	return p.decode(values, v)
+}
+
+// ReadPathParam reads the value of the path parameter p, as the router
+// matched it, into v
+func ReadPathParam(value string, p Param, v any) error {
+	var values paramValues
+	if value != "" {
+		var err error
+		values, err = p.pathValues(value)
+		if err != nil {
+			return ErrorBadRequest(fmt.Errorf("invalid path parameter %s: %w", p.Name, err))
+		}
+	}
+	return p.decode(values, v)
+}
+
+// paramValues are the serialized values of a parameter
+type paramValues struct {
+	present bool
+	// raw is the value of a scalar
+	raw string
+	// list are the items of an array
+	list []string
+	// pairs are the names and values of the properties of an object
+	pairs []string
+}
+
+func (p Param) queryValues(r *http.Request, v any) paramValues {
+	query := r.URL.Query()
+	switch {
+	case p.Style == "deepObject":
+		var values paramValues
+		for key, list := range query {
+			if name, ok := strings.CutPrefix(key, p.Name+"["); ok && strings.HasSuffix(name, "]") && len(list) > 0 {
+				values.present = true
+				values.pairs = append(values.pairs, strings.TrimSuffix(name, "]"), list[0])
+			}
+		}
+		return values
+	case p.Explode:
+		// Exploded objects are a query parameter per property
+		if properties, ok := objectProperties(v); ok {
+			var values paramValues
+			for key, list := range query {
+				if (properties == nil || properties[key]) && len(list) > 0 && list[0] != "" {
+					values.present = true
+					values.pairs = append(values.pairs, key, list[0])
+				}
+			}
+			return values
+		}
+		list := nonEmpty(query[p.Name])
+		if len(list) == 0 {
+			return paramValues{}
+		}
+		return paramValues{present: true, raw: list[0], list: list}
+	}
+
+	value := query.Get(p.Name)
+	if value == "" {
+		return paramValues{}
+	}
+	switch p.Style {
+	case "spaceDelimited":
+		return delimitedValues(value, " ")
+	case "pipeDelimited":
+		return delimitedValues(value, "|")
+	default:
+		return delimitedValues(value, ",")
+	}
+}
+
+// simpleValues reads a value in the simple style, like 3,4,5, or
+// role=admin,name=Alex for an exploded object
+func (p Param) simpleValues(value string) paramValues {
+	if value == "" {
+		return paramValues{}
+	}
+	if p.Explode {
+		return explodedValues(value, strings.Split(value, ","))
+	}
+	return delimitedValues(value, ",")
+}
+
+// delimitedValues reads a value that isn't exploded, like 3,4,5 or
+// role,admin,name,Alex
+func delimitedValues(value, sep string) paramValues {
+	list := strings.Split(value, sep)
+	return paramValues{present: true, raw: value, list: list, pairs: list}
+}
+
+// explodedValues reads the items of a value that may be properties, like
+// role=admin
+func explodedValues(value string, items []string) paramValues {
+	values := paramValues{present: true, raw: value, list: items}
+	for _, item := range items {
+		name, value, _ := strings.Cut(item, "=")
+		values.pairs = append(values.pairs, name, value)
+	}
+	return values
+}
+
+// pathValues reads a path parameter in the simple, label or matrix style
+func (p Param) pathValues(value string) (paramValues, error) {
+	switch p.Style {
+	case "label":
+		label, ok := strings.CutPrefix(value, ".")
+		if !ok {
+			return paramValues{}, fmt.Errorf("expected a label like .%s", value)
+		}
+		if p.Explode {
+			return explodedValues(label, strings.Split(label, ".")), nil
+		}
+		return delimitedValues(label, ","), nil
+	case "matrix":
+		matrix, ok := strings.CutPrefix(value, ";")
+		if !ok {
+			return paramValues{}, fmt.Errorf("expected a matrix like ;%s=%s", p.Name, value)
+		}
+		if p.Explode {
+			values := explodedValues(strings.TrimPrefix(matrix, p.Name+"="), strings.Split(matrix, ";"))
+			// The items of exploded arrays are each named after the parameter
+			for i, item := range values.list {
+				values.list[i] = strings.TrimPrefix(item, p.Name+"=")
+			}
+			return values, nil
+		}
+		matrix, ok = strings.CutPrefix(matrix, p.Name+"=")
+		if !ok {
+			return paramValues{}, fmt.Errorf("expected a matrix like ;%s=%s", p.Name, value)
+		}
+		return delimitedValues(matrix, ","), nil
+	default:
+		return p.simpleValues(value), nil
+	}
+}
+
+// decode sets v to the values of the parameter, or to its default when it
+// is missing
+func (p Param) decode(values paramValues, v any) error {
+	if !values.present {
+		if p.Required {
+			return ErrorBadRequest(fmt.Errorf("missing %s parameter %s", p.In, p.Name))
+		}
+		if p.Default != "" {
+			if err := json.Unmarshal([]byte(p.Default), v); err != nil {
+				return fmt.Errorf("invalid default of %s parameter %s: %w", p.In, p.Name, err)
+			}
+		}
+		return nil
+	}
+	if err := decodeParam(values, v); err != nil {
+		return ErrorBadRequest(fmt.Errorf("invalid %s parameter %s: %w", p.In, p.Name, err))
+	}
+	return nil
+}
+
+func decodeParam(values paramValues, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("parameter target must be a non-nil pointer")
+	}
+	elem := rv.Elem()
+
+	// Optional parameters may be pointers
+	if elem.Kind() == reflect.Pointer {
+		target := reflect.New(elem.Type().Elem())
+		if err := decodeParam(values, target.Interface()); err != nil {
+			return err
+		}
+		elem.Set(target)
+		return nil
+	}
+
+	if _, ok := v.(encoding.TextUnmarshaler); !ok {
+		switch {
+		case elem.Kind() == reflect.Slice && elem.Type().Elem().Kind() != reflect.Uint8:
+			items := reflect.MakeSlice(elem.Type(), len(values.list), len(values.list))
+			for i, item := range values.list {
+				if err := ParseParam(item, items.Index(i).Addr().Interface()); err != nil {
+					return err
+				}
+			}
+			elem.Set(items)
+			return nil
+		case elem.Kind() == reflect.Map && elem.Type().Key().Kind() == reflect.String:
+			return decodeMap(values.pairs, elem)
+		case elem.Kind() == reflect.Struct:
+			return decodeStruct(values.pairs, elem)
+		}
+	}
+
+	return ParseParam(values.raw, v)
+}
+
+// objectProperties reports whether v points to an object, and returns the
+// names of its properties, or nil for a map, which takes any property
+func objectProperties(v any) (map[string]bool, bool) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		if t.Implements(textUnmarshalerType) {
+			return nil, false
+		}
+		t = t.Elem()
+	}
+	switch {
+	case t == nil:
+		return nil, false
+	case t.Kind() == reflect.Map:
+		return nil, true
+	case t.Kind() != reflect.Struct:
+		return nil, false
+	}
+	properties := make(map[string]bool)
+	for name := range structFields(t) {
+		properties[name] = true
+	}
+	return properties, true
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// structFields returns the indexes of the fields of a struct by their json name
+func structFields(t reflect.Type) map[string]int {
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = i
+	}
+	return fields
+}
+
+func decodeMap(pairs []string, m reflect.Value) error {
+	if len(pairs)%2 != 0 {
+		return fmt.Errorf("expected pairs of property names and values")
+	}
+	result := reflect.MakeMap(m.Type())
+	for i := 0; i < len(pairs); i += 2 {
+		value := reflect.New(m.Type().Elem())
+		if err := ParseParam(pairs[i+1], value.Interface()); err != nil {
+			return fmt.Errorf("property %s: %w", pairs[i], err)
+		}
+		result.SetMapIndex(reflect.ValueOf(pairs[i]).Convert(m.Type().Key()), value.Elem())
+	}
+	m.Set(result)
+	return nil
+}
+
+// decodeStruct sets the fields of s named by their json tag. Pairs that
+// aren't one of its fields are ignored, since exploded form objects share
+// the query with other parameters.
+func decodeStruct(pairs []string, s reflect.Value) error {
+	if len(pairs)%2 != 0 {
+		return fmt.Errorf("expected pairs of property names and values")
+	}
+	fields := structFields(s.Type())
+	for i := 0; i < len(pairs); i += 2 {
+		index, ok := fields[pairs[i]]
+		if !ok {
+			continue
+		}
+		if err := ParseParam(pairs[i+1], s.Field(index).Addr().Interface()); err != nil {
+			return fmt.Errorf("property %s: %w", pairs[i], err)
+		}
+	}
+	return nil
+}
+
+func nonEmpty(list []string) []string {
+	var result []string
+	for _, value := range list {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// encodeParam returns the values to serialize for v, the way decodeParam
+// reads them: raw for a scalar, list for an array and pairs for an object.
+// Nil pointers, slices and maps aren't present.
+func encodeParam(v any) (paramValues, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return paramValues{}, nil
+		}
+		if _, ok := rv.Interface().(encoding.TextMarshaler); ok {
+			break
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return paramValues{}, nil
+	}
+
+	if _, ok := rv.Interface().(encoding.TextMarshaler); !ok {
+		switch {
+		case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8:
+			if rv.IsNil() {
+				return paramValues{}, nil
+			}
+			list := make([]string, 0, rv.Len())
+			for i := 0; i < rv.Len(); i++ {
+				item, err := FormatParam(rv.Index(i).Interface())
+				if err != nil {
+					return paramValues{}, err
+				}
+				list = append(list, item)
+			}
+			return paramValues{present: true, list: list}, nil
+		case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+			if rv.IsNil() {
+				return paramValues{}, nil
+			}
+			keys := make([]string, 0, rv.Len())
+			for _, key := range rv.MapKeys() {
+				keys = append(keys, key.String())
+			}
+			sort.Strings(keys)
+			pairs := make([]string, 0, 2*len(keys))
+			for _, key := range keys {
+				value, err := FormatParam(rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())).Interface())
+				if err != nil {
+					return paramValues{}, fmt.Errorf("property %s: %w", key, err)
+				}
+				pairs = append(pairs, key, value)
+			}
+			return paramValues{present: true, pairs: pairs}, nil
+		case rv.Kind() == reflect.Struct:
+			return encodeStruct(rv)
+		}
+	}
+
+	raw, err := FormatParam(rv.Interface())
+	if err != nil {
+		return paramValues{}, err
+	}
+	return paramValues{present: true, raw: raw}, nil
+}
+
+// encodeStruct returns the pairs of the fields of s by their json name,
+// leaving out nil fields and empty ones tagged omitempty
+func encodeStruct(s reflect.Value) (paramValues, error) {
+	pairs := []string{}
+	for i := 0; i < s.NumField(); i++ {
+		field := s.Type().Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		value := s.Field(i)
+		if value.IsZero() && (strings.Contains(options, "omitempty") || canBeNil(value)) {
+			continue
+		}
+		text, err := FormatParam(value.Interface())
+		if err != nil {
+			return paramValues{}, fmt.Errorf("property %s: %w", name, err)
+		}
+		pairs = append(pairs, name, text)
+	}
+	return paramValues{present: true, pairs: pairs}, nil
+}
+
+func canBeNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// items returns the serialized items of values: its list for an array, the
+// names and values of an object's properties joined by = when exploded or
+// one after the other when not, or else the scalar
+func (values paramValues) items(explode bool, escape func(string) string) []string {
+	switch {
+	case values.list != nil:
+		return mapStrings(values.list, escape)
+	case values.pairs != nil && explode:
+		items := make([]string, 0, len(values.pairs)/2)
+		for i := 0; i < len(values.pairs); i += 2 {
+			items = append(items, escape(values.pairs[i])+"="+escape(values.pairs[i+1]))
+		}
+		return items
+	case values.pairs != nil:
+		return mapStrings(values.pairs, escape)
+	}
+	return []string{escape(values.raw)}
+}
+
+// isScalar reports whether values are of a scalar
+func (values paramValues) isScalar() bool {
+	return values.list == nil && values.pairs == nil
+}
+
+func mapStrings(list []string, f func(string) string) []string {
+	result := make([]string, len(list))
+	for i, s := range list {
+		result[i] = f(s)
+	}
+	return result
+}
+
+func identity(s string) string { return s }
+
+// writeQuery adds the values of the query parameter p to query
+func (p Param) writeQuery(query url.Values, values paramValues) {
+	switch {
+	case p.Style == "deepObject":
+		for i := 0; i+1 < len(values.pairs); i += 2 {
+			query.Add(p.Name+"["+values.pairs[i]+"]", values.pairs[i+1])
+		}
+	case values.isScalar():
+		query.Add(p.Name, values.raw)
+	case p.Explode && values.pairs != nil:
+		for i := 0; i < len(values.pairs); i += 2 {
+			query.Add(values.pairs[i], values.pairs[i+1])
+		}
+	case p.Explode:
+		for _, item := range values.list {
+			query.Add(p.Name, item)
+		}
+	default:
+		sep := ","
+		switch p.Style {
+		case "spaceDelimited":
+			sep = " "
+		case "pipeDelimited":
+			sep = "|"
+		}
+		query.Add(p.Name, strings.Join(values.items(false, identity), sep))
+	}
+}
+
+// pathValue returns the path segment of the values of the path parameter
+// p, in the simple, label or matrix style
+func (p Param) pathValue(values paramValues) string {
+	items := values.items(p.Explode, url.PathEscape)
+	switch p.Style {
+	case "label":
+		if p.Explode {
+			return "." + strings.Join(items, ".")
+		}
+		return "." + strings.Join(items, ",")
+	case "matrix":
+		name := url.PathEscape(p.Name)
+		switch {
+		case values.isScalar() || !p.Explode:
+			return ";" + name + "=" + strings.Join(items, ",")
+		case values.list != nil:
+			return ";" + name + "=" + strings.Join(items, ";"+name+"=")
+		default:
+			return ";" + strings.Join(items, ";")
+		}
+	default:
+		return strings.Join(items, ",")
+	}
+}
+
+// headerValue returns the value of a header or cookie parameter
+func (p Param) headerValue(values paramValues) string {
+	return strings.Join(values.items(p.Explode && p.In == "header", identity), ",")
+}
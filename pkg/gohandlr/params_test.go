@@ -0,0 +1,234 @@
+package gohandlr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type filter struct {
+	Role string `json:"role"`
+	Age  int    `json:"age,omitempty"`
+}
+
+func TestReadParam(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		param    Param
+		v        any
+		expected any
+	}{
+		{"form", "/?limit=10", Param{Name: "limit", In: "query", Style: "form", Explode: true}, new(int), 10},
+		{"form bool", "/?active=true", Param{Name: "active", In: "query", Style: "form", Explode: true}, new(bool), true},
+		{"form float", "/?price=9.5", Param{Name: "price", In: "query", Style: "form", Explode: true}, new(float64), 9.5},
+		{"form pointer", "/?limit=10", Param{Name: "limit", In: "query", Style: "form", Explode: true}, new(*int), ptr(10)},
+		{"form exploded array", "/?id=3&id=4", Param{Name: "id", In: "query", Style: "form", Explode: true}, new([]int), []int{3, 4}},
+		{"form array", "/?id=3,4", Param{Name: "id", In: "query", Style: "form"}, new([]int), []int{3, 4}},
+		{"form exploded object", "/?role=admin&age=5&limit=1", Param{Name: "filter", In: "query", Style: "form", Explode: true}, new(filter), filter{Role: "admin", Age: 5}},
+		{"form object", "/?filter=role,admin,age,5", Param{Name: "filter", In: "query", Style: "form"}, new(filter), filter{Role: "admin", Age: 5}},
+		{"spaceDelimited", "/?id=3%204", Param{Name: "id", In: "query", Style: "spaceDelimited"}, new([]int), []int{3, 4}},
+		{"pipeDelimited", "/?id=3|4", Param{Name: "id", In: "query", Style: "pipeDelimited"}, new([]string), []string{"3", "4"}},
+		{"deepObject", "/?filter[role]=admin&filter[age]=5", Param{Name: "filter", In: "query", Style: "deepObject", Explode: true}, new(filter), filter{Role: "admin", Age: 5}},
+		{"deepObject map", "/?tags[a]=1&tags[b]=2", Param{Name: "tags", In: "query", Style: "deepObject", Explode: true}, new(map[string]int), map[string]int{"a": 1, "b": 2}},
+		{"default", "/", Param{Name: "limit", In: "query", Style: "form", Explode: true, Default: "20"}, new(int), 20},
+		{"default array", "/", Param{Name: "id", In: "query", Style: "form", Explode: true, Default: `["a"]`}, new([]string), []string{"a"}},
+		{"missing", "/", Param{Name: "limit", In: "query", Style: "form", Explode: true}, new(int), 0},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, test.target, nil)
+		if err := ReadParam(r, test.param, test.v); err != nil {
+			t.Errorf("%s: failed to read parameter: %v", test.name, err)
+			continue
+		}
+		if actual := reflect.ValueOf(test.v).Elem().Interface(); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected value: %v, got: %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestReadParamHeaderAndCookie(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Agent", "Mozilla/5.0 (X11, Linux)")
+	r.Header.Set("X-Ids", "3,4")
+	r.Header.Set("X-Filter", "role=admin,age=5")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+	var agent string
+	if err := ReadParam(r, Param{Name: "X-Agent", In: "header", Style: "simple"}, &agent); err != nil || agent != "Mozilla/5.0 (X11, Linux)" {
+		t.Errorf("Expected header: %s, got: %s (%v)", "Mozilla/5.0 (X11, Linux)", agent, err)
+	}
+	var ids []int
+	if err := ReadParam(r, Param{Name: "X-Ids", In: "header", Style: "simple"}, &ids); err != nil || !reflect.DeepEqual(ids, []int{3, 4}) {
+		t.Errorf("Expected header: %v, got: %v (%v)", []int{3, 4}, ids, err)
+	}
+	var f filter
+	if err := ReadParam(r, Param{Name: "X-Filter", In: "header", Style: "simple", Explode: true}, &f); err != nil || f != (filter{Role: "admin", Age: 5}) {
+		t.Errorf("Expected header: %v, got: %v (%v)", filter{Role: "admin", Age: 5}, f, err)
+	}
+	var session string
+	if err := ReadParam(r, Param{Name: "session", In: "cookie", Style: "form", Explode: true, Required: true}, &session); err != nil || session != "abc" {
+		t.Errorf("Expected cookie: %s, got: %s (%v)", "abc", session, err)
+	}
+}
+
+func TestReadPathParam(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		param    Param
+		v        any
+		expected any
+	}{
+		{"simple", "5", Param{Name: "id", Style: "simple"}, new(int), 5},
+		{"simple array", "3,4", Param{Name: "id", Style: "simple"}, new([]int), []int{3, 4}},
+		{"simple object", "role,admin,age,5", Param{Name: "id", Style: "simple"}, new(filter), filter{Role: "admin", Age: 5}},
+		{"simple exploded object", "role=admin,age=5", Param{Name: "id", Style: "simple", Explode: true}, new(filter), filter{Role: "admin", Age: 5}},
+		{"label", ".5", Param{Name: "id", Style: "label"}, new(int), 5},
+		{"label array", ".3,4", Param{Name: "id", Style: "label"}, new([]int), []int{3, 4}},
+		{"label exploded array", ".3.4", Param{Name: "id", Style: "label", Explode: true}, new([]int), []int{3, 4}},
+		{"label exploded object", ".role=admin.age=5", Param{Name: "id", Style: "label", Explode: true}, new(filter), filter{Role: "admin", Age: 5}},
+		{"matrix", ";id=5", Param{Name: "id", Style: "matrix"}, new(int), 5},
+		{"matrix array", ";id=3,4", Param{Name: "id", Style: "matrix"}, new([]int), []int{3, 4}},
+		{"matrix exploded array", ";id=3;id=4", Param{Name: "id", Style: "matrix", Explode: true}, new([]int), []int{3, 4}},
+		{"matrix exploded object", ";role=admin;age=5", Param{Name: "id", Style: "matrix", Explode: true}, new(filter), filter{Role: "admin", Age: 5}},
+	}
+	for _, test := range tests {
+		test.param.In = "path"
+		test.param.Required = true
+		if err := ReadPathParam(test.value, test.param, test.v); err != nil {
+			t.Errorf("%s: failed to read parameter: %v", test.name, err)
+			continue
+		}
+		if actual := reflect.ValueOf(test.v).Elem().Interface(); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected value: %v, got: %v", test.name, test.expected, actual)
+		}
+	}
+
+	var id int
+	if err := ReadPathParam("5", Param{Name: "id", In: "path", Style: "matrix", Required: true}, &id); err == nil {
+		t.Errorf("Expected a value without a matrix prefix to fail")
+	}
+}
+
+func TestReadParamErrors(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?limit=ten", nil)
+
+	var limit int
+	err := ReadParam(r, Param{Name: "limit", In: "query", Style: "form", Explode: true}, &limit)
+	var apiErr Error
+	if !errors.As(err, &apiErr) || apiErr.Status() != http.StatusBadRequest {
+		t.Fatalf("Expected a bad request, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "limit") {
+		t.Errorf("Expected the error to name the parameter, got: %s", err)
+	}
+
+	err = ReadParam(r, Param{Name: "X-Request-ID", In: "header", Style: "simple", Required: true}, new(string))
+	if !errors.As(err, &apiErr) || apiErr.Status() != http.StatusBadRequest {
+		t.Fatalf("Expected a bad request, got: %v", err)
+	}
+	if expected := "missing header parameter X-Request-ID"; !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected error: %s, got: %s", expected, err)
+	}
+
+	err = ReadParam(r, Param{Name: "filter", In: "query", Style: "form", Explode: true, Required: true}, new(filter))
+	if err == nil {
+		t.Errorf("Expected an exploded object without its properties to be missing")
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestWriteParamRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		param Param
+		value any
+	}{
+		{"form", Param{Name: "limit", In: "query", Style: "form", Explode: true}, 10},
+		{"form exploded array", Param{Name: "id", In: "query", Style: "form", Explode: true}, []int{3, 4}},
+		{"form array", Param{Name: "id", In: "query", Style: "form"}, []string{"a b", "c&d"}},
+		{"form exploded object", Param{Name: "filter", In: "query", Style: "form", Explode: true}, filter{Role: "admin", Age: 5}},
+		{"form object", Param{Name: "filter", In: "query", Style: "form"}, filter{Role: "admin"}},
+		{"spaceDelimited", Param{Name: "id", In: "query", Style: "spaceDelimited"}, []int{3, 4}},
+		{"pipeDelimited", Param{Name: "id", In: "query", Style: "pipeDelimited"}, []string{"a", "b"}},
+		{"deepObject", Param{Name: "filter", In: "query", Style: "deepObject", Explode: true}, filter{Role: "admin", Age: 5}},
+		{"deepObject map", Param{Name: "tags", In: "query", Style: "deepObject", Explode: true}, map[string]int{"a": 1, "b": 2}},
+		{"header", Param{Name: "X-Agent", In: "header", Style: "simple"}, "Mozilla/5.0 (X11, Linux)"},
+		{"header array", Param{Name: "X-Ids", In: "header", Style: "simple"}, []int{3, 4}},
+		{"header exploded object", Param{Name: "X-Filter", In: "header", Style: "simple", Explode: true}, filter{Role: "admin", Age: 5}},
+		{"cookie", Param{Name: "session", In: "cookie", Style: "form", Explode: true}, "abc"},
+		{"cookie array", Param{Name: "ids", In: "cookie", Style: "form"}, []int{3, 4}},
+		{"path", Param{Name: "id", In: "path", Style: "simple"}, "a/b c"},
+		{"path array", Param{Name: "id", In: "path", Style: "simple"}, []int{3, 4}},
+		{"path exploded object", Param{Name: "id", In: "path", Style: "simple", Explode: true}, filter{Role: "admin", Age: 5}},
+		{"label", Param{Name: "id", In: "path", Style: "label"}, 5},
+		{"label exploded array", Param{Name: "id", In: "path", Style: "label", Explode: true}, []int{3, 4}},
+		{"matrix", Param{Name: "id", In: "path", Style: "matrix"}, 5},
+		{"matrix array", Param{Name: "id", In: "path", Style: "matrix"}, []int{3, 4}},
+		{"matrix exploded array", Param{Name: "id", In: "path", Style: "matrix", Explode: true}, []int{3, 4}},
+		{"matrix exploded object", Param{Name: "id", In: "path", Style: "matrix", Explode: true}, filter{Role: "admin", Age: 5}},
+	}
+	for _, test := range tests {
+		c := NewClient("http://example.com")
+		r, err := c.newRequest(context.Background(), Request{Method: http.MethodGet, Path: "/items/{id}", Params: []ParamValue{{test.param, test.value}}})
+		if err != nil {
+			t.Errorf("%s: failed to write parameter: %v", test.name, err)
+			continue
+		}
+
+		actual := reflect.New(reflect.TypeOf(test.value))
+		if test.param.In == "path" {
+			value, _ := strings.CutPrefix(r.URL.EscapedPath(), "/items/")
+			unescaped, _ := url.PathUnescape(value)
+			// The server matches the escaped segment and unescapes it
+			if strings.Contains(value, "/") {
+				t.Errorf("%s: expected the path segment to be escaped, got: %s", test.name, value)
+			}
+			err = ReadPathParam(unescaped, test.param, actual.Interface())
+		} else {
+			// The request goes through the wire to get its cookies parsed
+			sent := httptest.NewRequest(http.MethodGet, r.URL.String(), nil)
+			sent.Header = r.Header
+			err = ReadParam(sent, test.param, actual.Interface())
+		}
+		if err != nil {
+			t.Errorf("%s: failed to read parameter from %s %v: %v", test.name, r.URL, r.Header, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual.Elem().Interface(), test.value) {
+			t.Errorf("%s: expected value: %v, got: %v", test.name, test.value, actual.Elem().Interface())
+		}
+	}
+}
+
+func TestWriteParamOptional(t *testing.T) {
+	c := NewClient("http://example.com")
+	r, err := c.newRequest(context.Background(), Request{Method: http.MethodGet, Path: "/items", Params: []ParamValue{
+		{Param{Name: "limit", In: "query", Style: "form", Explode: true}, Ptr(0)},
+		{Param{Name: "tags", In: "query", Style: "form", Explode: true}, []string(nil)},
+		{Param{Name: "after", In: "query", Style: "form", Explode: true}, (*int)(nil)},
+		{Param{Name: "page", In: "query", Style: "form", Explode: true, Required: true}, 0},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to write parameters: %v", err)
+	}
+	if expected := "limit=0&page=0"; r.URL.RawQuery != expected {
+		t.Errorf("Expected query: %s, got: %s", expected, r.URL.RawQuery)
+	}
+
+	_, err = c.newRequest(context.Background(), Request{Method: http.MethodGet, Path: "/items", Params: []ParamValue{
+		{Param{Name: "after", In: "query", Style: "form", Explode: true, Required: true}, (*int)(nil)},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "missing query parameter after") {
+		t.Errorf("Expected a nil required parameter to be missing, got: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package gohandlr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitResult is the outcome of a request against a rate limit
+type RateLimitResult struct {
+	Allowed bool
+	// Limit is the number of requests allowed at once
+	Limit int
+	// Remaining is the number of requests left before the limit is reached
+	Remaining int
+	// Reset is the time until the full limit is available again
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, when it isn't
+	RetryAfter time.Duration
+	// Policy describes the limit in the RateLimit-Policy header format, like "100;w=60"
+	Policy string
+}
+
+// Limiter decides whether the requests for a key are within their limit
+type Limiter interface {
+	Allow(ctx context.Context, key string) (RateLimitResult, error)
+}
+
+// KeyFunc identifies who a request is limited as, like a client IP or API key
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP limits requests by the address of the client
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader limits requests by the value of a header, like an API key
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// RateLimit configures how requests are throttled
+type RateLimit struct {
+	Limiter Limiter
+	Key     KeyFunc
+}
+
+// WithRateLimit throttles requests per key. Requests over the limit get 429
+// Too Many Requests with a Retry-After header, and every response carries the
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers.
+func WithRateLimit(limiter Limiter, key KeyFunc) Option {
+	return func(c *Config) {
+		c.RateLimit = &RateLimit{Limiter: limiter, Key: key}
+	}
+}
+
+func (l *RateLimit) middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := l.Limiter.Allow(r.Context(), l.Key(r))
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		header := w.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+		if result.Policy != "" {
+			header.Set("RateLimit-Policy", result.Policy)
+		}
+
+		if !result.Allowed {
+			header.Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+			writeError(w, ErrorTooManyRequests(errors.New("rate limit exceeded")), http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// seconds rounds a duration up to whole seconds, as the headers require
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// MemoryLimiter is a token bucket Limiter that keeps its buckets in memory
+type MemoryLimiter struct {
+	rate   float64 // tokens added per second
+	burst  int
+	policy string
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewMemoryLimiter allows requests per period for each key, with bursts of up
+// to burst requests. A burst below one allows requests at once.
+func NewMemoryLimiter(requests int, per time.Duration, burst int) *MemoryLimiter {
+	if burst < 1 {
+		burst = requests
+	}
+	return &MemoryLimiter{
+		rate:    float64(requests) / per.Seconds(),
+		burst:   burst,
+		policy:  fmt.Sprintf("%d;w=%d", requests, seconds(per)),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		l.sweep(now)
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+
+	// Refill the bucket for the time since the last request
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	result := RateLimitResult{
+		Limit:  l.burst,
+		Policy: l.policy,
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.duration(1 - b.tokens)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = l.duration(float64(l.burst) - b.tokens)
+	return result, nil
+}
+
+// duration is how long it takes to refill the given number of tokens
+func (l *MemoryLimiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// sweep drops buckets that have refilled completely, as they are the same as new ones
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if len(l.buckets) < 1024 {
+		return
+	}
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
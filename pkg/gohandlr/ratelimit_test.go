@@ -0,0 +1,76 @@
+package gohandlr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewMemoryLimiter(2, time.Second, 0)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		result, _ := limiter.Allow(context.Background(), "a")
+		if !result.Allowed {
+			t.Fatalf("Expected request %d to be allowed", i)
+		}
+	}
+
+	result, _ := limiter.Allow(context.Background(), "a")
+	if result.Allowed {
+		t.Fatalf("Expected the third request to be limited")
+	}
+	if result.RetryAfter != 500*time.Millisecond {
+		t.Errorf("Expected retry after: %s, got: %s", 500*time.Millisecond, result.RetryAfter)
+	}
+
+	// Other keys have their own bucket
+	if result, _ := limiter.Allow(context.Background(), "b"); !result.Allowed {
+		t.Errorf("Expected a different key to be allowed")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	result, _ = limiter.Allow(context.Background(), "a")
+	if !result.Allowed {
+		t.Errorf("Expected a request to be allowed after the bucket refilled")
+	}
+	if result.Remaining != 0 {
+		t.Errorf("Expected remaining: %d, got: %d", 0, result.Remaining)
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	handler := HandlerNoRequestNoResponse(func(ctx context.Context) error {
+		return nil
+	}, WithRateLimit(NewMemoryLimiter(1, time.Minute, 0), KeyByHeader("X-API-Key")))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", "secret")
+
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusNoContent, rec.Code)
+	}
+	if got := rec.Header().Get("RateLimit-Policy"); got != "1;w=60" {
+		t.Errorf("Expected policy: %s, got: %s", "1;w=60", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusTooManyRequests, rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Expected retry after: %s, got: %s", "60", got)
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("Expected remaining: %s, got: %s", "0", got)
+	}
+}
@@ -0,0 +1,116 @@
+package gohandlr
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"reflect"
+)
+
+// StatusResponse is a response with a status of its own. Handlers write its
+// headers, then its body like any other response, with its status instead of
+// 200 OK. The variants of the response types generated for operations with
+// several success responses implement it.
+type StatusResponse interface {
+	// ResponseStatus returns the status code of the response
+	ResponseStatus() int
+	// WriteResponseHeader sets the headers of the response
+	WriteResponseHeader(header http.Header)
+	// ResponseBody returns the body of the response, nil without one
+	ResponseBody() any
+}
+
+var statusResponseType = reflect.TypeOf((*StatusResponse)(nil)).Elem()
+
+// asStatusResponse returns the StatusResponse v is, or points to. ok is
+// true for a nil response of a StatusResponse type too, which can't be
+// written.
+func asStatusResponse(v any) (StatusResponse, bool) {
+	if resp, ok := v.(StatusResponse); ok {
+		return resp, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || !rv.Type().Elem().Implements(statusResponseType) {
+		return nil, false
+	}
+	if rv.Elem().Kind() == reflect.Interface && rv.Elem().IsNil() {
+		return nil, true
+	}
+	return rv.Elem().Interface().(StatusResponse), true
+}
+
+// writeStatusResponse writes resp with its status and headers
+func (c *Config) writeStatusResponse(r *http.Request, w http.ResponseWriter, resp StatusResponse) error {
+	if resp == nil {
+		return errors.New("no response to write, return one of the variants of the response type")
+	}
+	resp.WriteResponseHeader(w.Header())
+	body := resp.ResponseBody()
+	if body == nil {
+		w.WriteHeader(resp.ResponseStatus())
+		return nil
+	}
+	return c.Marshal(r, &statusWriter{ResponseWriter: w, status: resp.ResponseStatus()}, body)
+}
+
+// statusWriter writes status instead of 200 OK, including when the body is
+// written without a status first
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if status == http.StatusOK {
+		status = w.status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(w.status)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// SetResponseHeader sets the header name to v, in the simple style of header
+// parameters. Zero values aren't set.
+func SetResponseHeader(header http.Header, name string, v any) {
+	values, err := encodeParam(v)
+	if err != nil || !values.present || isZero(v) {
+		return
+	}
+	header.Set(name, Param{Name: name, In: "header", Style: "simple"}.headerValue(values))
+}
+
+// isZero reports whether v is the zero value of its type, which response
+// headers aren't set for
+func isZero(v any) bool {
+	if v == nil {
+		return true
+	}
+	return reflect.ValueOf(v).IsZero()
+}
+
+// ReadResponseHeader reads the header name of a response into v, the way
+// ReadParam reads a header parameter
+func ReadResponseHeader(header http.Header, name string, v any) error {
+	return ReadParam(&http.Request{Header: header}, Param{Name: name, In: "header", Style: "simple"}, v)
+}
+
+// ResponseMediaType returns the media type of a response, without its
+// parameters
+func ResponseMediaType(res *http.Response) string {
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	return mediaType
+}
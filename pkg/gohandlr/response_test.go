@@ -0,0 +1,162 @@
+package gohandlr
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// jobResponse is the response of an operation that creates a job, or
+// accepts it to create it later
+type jobResponse interface {
+	StatusResponse
+	isJobResponse()
+}
+
+type jobCreated struct {
+	Location string
+	Body     pet
+}
+
+func (jobCreated) isJobResponse()        {}
+func (r jobCreated) ResponseStatus() int { return http.StatusCreated }
+func (r jobCreated) WriteResponseHeader(header http.Header) {
+	SetResponseHeader(header, "Location", r.Location)
+}
+func (r jobCreated) ResponseBody() any { return r.Body }
+
+type jobAccepted struct {
+	RetryAfter int
+}
+
+func (jobAccepted) isJobResponse()        {}
+func (r jobAccepted) ResponseStatus() int { return http.StatusAccepted }
+func (r jobAccepted) WriteResponseHeader(header http.Header) {
+	SetResponseHeader(header, "Retry-After", r.RetryAfter)
+}
+func (r jobAccepted) ResponseBody() any { return nil }
+
+func jobHandler(resp jobResponse, options ...Option) http.HandlerFunc {
+	return HandlerNoRequestWithResponse(func(ctx context.Context) (jobResponse, error) {
+		return resp, nil
+	}, options...)
+}
+
+func TestStatusResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    jobResponse
+		options []Option
+		status  int
+		header  string
+		body    string
+	}{
+		{"body", jobCreated{Location: "/jobs/1", Body: pet{ID: 1}}, nil, http.StatusCreated, "/jobs/1", `{"id":1,"name":""}` + "\n"},
+		{"buffered", jobCreated{Location: "/jobs/1", Body: pet{ID: 1}}, []Option{WithETag()}, http.StatusCreated, "/jobs/1", `{"id":1,"name":""}` + "\n"},
+		{"no body", jobAccepted{RetryAfter: 30}, nil, http.StatusAccepted, "30", ""},
+		{"zero header", jobAccepted{}, nil, http.StatusAccepted, "", ""},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		jobHandler(test.resp, test.options...)(rec, httptest.NewRequest(http.MethodPost, "/jobs", nil))
+
+		if rec.Code != test.status {
+			t.Errorf("%s: expected status code: %d, got: %d", test.name, test.status, rec.Code)
+		}
+		header := rec.Header().Get("Location") + rec.Header().Get("Retry-After")
+		if header != test.header {
+			t.Errorf("%s: expected header: %q, got: %q", test.name, test.header, header)
+		}
+		if rec.Body.String() != test.body {
+			t.Errorf("%s: expected body: %q, got: %q", test.name, test.body, rec.Body.String())
+		}
+	}
+
+	// A nil response is an error rather than an empty body
+	rec := httptest.NewRecorder()
+	jobHandler(nil)(rec, httptest.NewRequest(http.MethodPost, "/jobs", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+type reportFile struct {
+	Body File
+}
+
+func (r reportFile) ResponseStatus() int                    { return http.StatusNonAuthoritativeInfo }
+func (r reportFile) WriteResponseHeader(header http.Header) {}
+func (r reportFile) ResponseBody() any                      { return r.Body }
+
+func TestStatusResponseFile(t *testing.T) {
+	handler := HandlerNoRequestWithResponse(func(ctx context.Context) (StatusResponse, error) {
+		return reportFile{Body: NewFile(strings.NewReader("a,b"), "report.csv", "text/csv")}, nil
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/report", nil))
+
+	if rec.Code != http.StatusNonAuthoritativeInfo {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusNonAuthoritativeInfo, rec.Code)
+	}
+	if rec.Body.String() != "a,b" || rec.Header().Get("Content-Type") != "text/csv" {
+		t.Errorf("Expected the file, got: %q %s", rec.Body.String(), rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestResponseDecoder(t *testing.T) {
+	var resp jobResponse = jobAccepted{RetryAfter: 30}
+	server := httptest.NewServer(http.HandlerFunc(HandlerNoRequestWithResponse(func(ctx context.Context) (jobResponse, error) {
+		return resp, nil
+	})))
+	defer server.Close()
+
+	var decoded jobResponse
+	decode := ResponseDecoder(func(res *http.Response, body []byte) error {
+		switch res.StatusCode {
+		case http.StatusCreated:
+			var r jobCreated
+			if err := ReadResponseHeader(res.Header, "Location", &r.Location); err != nil {
+				return err
+			}
+			if err := json.Unmarshal(body, &r.Body); err != nil {
+				return err
+			}
+			decoded = r
+		case http.StatusAccepted:
+			var r jobAccepted
+			if err := ReadResponseHeader(res.Header, "Retry-After", &r.RetryAfter); err != nil {
+				return err
+			}
+			decoded = r
+		default:
+			return &ResponseError{StatusCode: res.StatusCode, Header: res.Header, Body: body}
+		}
+		return nil
+	})
+
+	c := NewClient(server.URL)
+	for _, expected := range []jobResponse{jobAccepted{RetryAfter: 30}, jobCreated{Location: "/jobs/1", Body: pet{ID: 1, Name: "Rex"}}} {
+		resp = expected
+		if err := c.Do(context.Background(), Request{Method: "POST", Path: "/"}, decode); err != nil {
+			t.Errorf("Failed to send request: %v", err)
+		}
+		if decoded != expected {
+			t.Errorf("Expected response: %+v, got: %+v", expected, decoded)
+		}
+	}
+}
+
+func TestResponseMediaType(t *testing.T) {
+	res := &http.Response{Header: http.Header{"Content-Type": {"application/json; charset=utf-8"}}}
+	if mediaType := ResponseMediaType(res); mediaType != "application/json" {
+		t.Errorf("Expected media type: %s, got: %s", "application/json", mediaType)
+	}
+	content, _ := io.ReadAll(ResponseFile(res, []byte("{}")).Content)
+	if string(content) != "{}" {
+		t.Errorf("Expected content: %s, got: %s", "{}", content)
+	}
+}
@@ -0,0 +1,247 @@
+package gohandlr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ErrNoCredentials is returned by a Scheme when the request doesn't carry its credentials
+var ErrNoCredentials = errors.New("no credentials")
+
+// Scheme authenticates a request with one security scheme and returns the
+// principal it belongs to. It returns an error wrapping ErrNoCredentials when
+// the request doesn't use the scheme, and ErrorForbidden when the principal
+// lacks scopes.
+type Scheme func(r *http.Request, scopes []string) (any, error)
+
+// SecurityRequirement maps the names of security schemes to the scopes they
+// need. A request meets the requirement when it passes every scheme.
+type SecurityRequirement map[string][]string
+
+// Security configures how requests are authenticated
+type Security struct {
+	Schemes map[string]Scheme
+	// Requirements are alternatives; a request must meet one of them
+	Requirements []SecurityRequirement
+}
+
+// WithSchemes adds the implementations of security schemes by name
+func WithSchemes(schemes map[string]Scheme) Option {
+	return func(c *Config) {
+		merged := maps.Clone(c.Security.Schemes)
+		if merged == nil {
+			merged = make(map[string]Scheme, len(schemes))
+		}
+		maps.Copy(merged, schemes)
+		c.Security.Schemes = merged
+	}
+}
+
+// WithSecurity requires requests to meet one of the requirements. Requests
+// without valid credentials get 401 Unauthorized, and requests that lack
+// scopes get 403 Forbidden. An empty requirement allows anonymous requests.
+func WithSecurity(requirements ...SecurityRequirement) Option {
+	return func(c *Config) {
+		c.Security.Requirements = requirements
+	}
+}
+
+type authenticationKey struct{}
+
+// authentication is the outcome of a successful security check
+type authentication struct {
+	principal  any
+	principals map[string]any
+}
+
+// Principal returns the principal that authenticated the request, or nil.
+// When a requirement uses several schemes it is the first principal in
+// order of the scheme names.
+func Principal(ctx context.Context) any {
+	auth, _ := ctx.Value(authenticationKey{}).(authentication)
+	return auth.principal
+}
+
+// Principals returns the principal of every scheme that authenticated the request
+func Principals(ctx context.Context) map[string]any {
+	auth, _ := ctx.Value(authenticationKey{}).(authentication)
+	return auth.principals
+}
+
+// PrincipalIdentifier is implemented by principals with a stable identity,
+// like a user ID, which idempotency keys and rate limits are scoped by
+type PrincipalIdentifier interface {
+	PrincipalID() string
+}
+
+// PrincipalID returns the stable identity of the principal of the request:
+// its PrincipalID, or the principal itself when it is a string. Other
+// principals, like pointers whose formatting changes between requests, have
+// none, and it returns an empty string for them.
+func PrincipalID(ctx context.Context) string {
+	switch p := Principal(ctx).(type) {
+	case PrincipalIdentifier:
+		return p.PrincipalID()
+	case string:
+		return p
+	}
+	return ""
+}
+
+// KeyByPrincipal limits requests by the identity of their principal, see
+// PrincipalID, or by client address when the request is anonymous or its
+// principal has no stable identity
+func KeyByPrincipal(r *http.Request) string {
+	if id := PrincipalID(r.Context()); id != "" {
+		return id
+	}
+	return KeyByIP(r)
+}
+
+func (s *Security) middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var failure error
+		var challenges []string
+		for _, requirement := range s.Requirements {
+			auth, err := s.authenticate(r, requirement)
+			if err == nil {
+				ctx := context.WithValue(r.Context(), authenticationKey{}, auth)
+				next(w, r.WithContext(ctx))
+				return
+			}
+
+			var c challengeError
+			if errors.As(err, &c) && !slices.Contains(challenges, c.challenge) {
+				challenges = append(challenges, c.challenge)
+			}
+			// A forbidden principal is more specific than missing credentials
+			if failure == nil || statusOf(err) == http.StatusForbidden {
+				failure = err
+			}
+		}
+
+		if statusOf(failure) == http.StatusUnauthorized {
+			for _, c := range challenges {
+				w.Header().Add("WWW-Authenticate", c)
+			}
+		}
+		writeError(w, failure, http.StatusUnauthorized)
+	}
+}
+
+// authenticate checks every scheme of a requirement, in order of their names
+func (s *Security) authenticate(r *http.Request, requirement SecurityRequirement) (authentication, error) {
+	names := make([]string, 0, len(requirement))
+	for name := range requirement {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	auth := authentication{principals: make(map[string]any, len(names))}
+	for _, name := range names {
+		scheme, ok := s.Schemes[name]
+		if !ok {
+			return auth, ErrorInternal(fmt.Errorf("security scheme %q is not configured", name))
+		}
+		principal, err := scheme(r, requirement[name])
+		if err != nil {
+			var e Error
+			if !errors.As(err, &e) {
+				err = ErrorUnauthorized(err)
+			}
+			return auth, err
+		}
+		auth.principals[name] = principal
+		if auth.principal == nil {
+			auth.principal = principal
+		}
+	}
+	return auth, nil
+}
+
+// challengeError is a missing credentials error with the WWW-Authenticate
+// challenge of its scheme
+type challengeError struct {
+	NewError
+	challenge string
+}
+
+func noCredentials(challenge string) error {
+	return challengeError{
+		NewError:  NewError{err: ErrNoCredentials, status: http.StatusUnauthorized},
+		challenge: challenge,
+	}
+}
+
+func statusOf(err error) int {
+	var e Error
+	if errors.As(err, &e) {
+		return e.Status()
+	}
+	return http.StatusUnauthorized
+}
+
+// BearerScheme reads a token from the Authorization: Bearer header. It is
+// used for HTTP bearer, OAuth2 and OpenID Connect schemes.
+func BearerScheme(authenticate func(ctx context.Context, token string, scopes []string) (any, error)) Scheme {
+	return func(r *http.Request, scopes []string) (any, error) {
+		auth := r.Header.Get("Authorization")
+		prefix, token, ok := strings.Cut(auth, " ")
+		if !ok || !strings.EqualFold(prefix, "Bearer") || strings.TrimSpace(token) == "" {
+			return nil, noCredentials("Bearer")
+		}
+		return authenticate(r.Context(), strings.TrimSpace(token), scopes)
+	}
+}
+
+// BasicScheme reads a username and password from the Authorization: Basic header
+func BasicScheme(authenticate func(ctx context.Context, username, password string, scopes []string) (any, error)) Scheme {
+	return func(r *http.Request, scopes []string) (any, error) {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			return nil, noCredentials("Basic")
+		}
+		return authenticate(r.Context(), username, password, scopes)
+	}
+}
+
+// APIKeyScheme reads an API key from the header, query parameter or cookie
+// with the given name. in is "header", "query" or "cookie".
+func APIKeyScheme(in, name string, authenticate func(ctx context.Context, key string, scopes []string) (any, error)) Scheme {
+	return func(r *http.Request, scopes []string) (any, error) {
+		var key string
+		switch in {
+		case "header":
+			key = r.Header.Get(name)
+		case "query":
+			key = r.URL.Query().Get(name)
+		case "cookie":
+			if cookie, err := r.Cookie(name); err == nil {
+				key = cookie.Value
+			}
+		}
+		if key == "" {
+			return nil, ErrorUnauthorized(ErrNoCredentials)
+		}
+		return authenticate(r.Context(), key, scopes)
+	}
+}
+
+// RequireScopes returns ErrorForbidden unless granted contains every required scope
+func RequireScopes(granted, required []string) error {
+	have := make(map[string]bool, len(granted))
+	for _, scope := range granted {
+		have[scope] = true
+	}
+	for _, scope := range required {
+		if !have[scope] {
+			return ErrorForbidden(fmt.Errorf("missing scope %q", scope))
+		}
+	}
+	return nil
+}
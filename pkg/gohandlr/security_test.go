@@ -0,0 +1,111 @@
+package gohandlr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func securityHandler(requirements ...SecurityRequirement) http.HandlerFunc {
+	bearer := BearerScheme(func(ctx context.Context, token string, scopes []string) (any, error) {
+		if token != "token" {
+			return nil, errors.New("invalid token")
+		}
+		return "bearer-user", RequireScopes([]string{"read"}, scopes)
+	})
+	apiKey := APIKeyScheme("query", "key", func(ctx context.Context, key string, scopes []string) (any, error) {
+		if key != "key" {
+			return nil, errors.New("invalid key")
+		}
+		return "key-user", nil
+	})
+
+	return HandlerNoRequestWithResponse(func(ctx context.Context) (string, error) {
+		principal, _ := Principal(ctx).(string)
+		return principal, nil
+	},
+		WithSchemes(map[string]Scheme{"bearer": bearer, "apiKey": apiKey}),
+		WithSecurity(requirements...),
+	)
+}
+
+func TestSecurity(t *testing.T) {
+	tests := []struct {
+		name         string
+		requirements []SecurityRequirement
+		target       string
+		token        string
+		status       int
+		body         string
+	}{
+		{"no credentials", []SecurityRequirement{{"bearer": {}}}, "/", "", http.StatusUnauthorized, ""},
+		{"invalid token", []SecurityRequirement{{"bearer": {}}}, "/", "nope", http.StatusUnauthorized, ""},
+		{"valid token", []SecurityRequirement{{"bearer": {"read"}}}, "/", "token", http.StatusOK, "\"bearer-user\"\n"},
+		{"missing scope", []SecurityRequirement{{"bearer": {"write"}}}, "/", "token", http.StatusForbidden, ""},
+		{"or", []SecurityRequirement{{"bearer": {}}, {"apiKey": {}}}, "/?key=key", "", http.StatusOK, "\"key-user\"\n"},
+		{"and missing one", []SecurityRequirement{{"bearer": {}, "apiKey": {}}}, "/?key=key", "", http.StatusUnauthorized, ""},
+		{"and", []SecurityRequirement{{"bearer": {}, "apiKey": {}}}, "/?key=key", "token", http.StatusOK, "\"key-user\"\n"},
+		{"optional", []SecurityRequirement{{"bearer": {}}, {}}, "/", "", http.StatusOK, "\"\"\n"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.target, nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		rec := httptest.NewRecorder()
+		securityHandler(test.requirements...)(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("%s: expected status code: %d, got: %d", test.name, test.status, rec.Code)
+		}
+		if test.body != "" && rec.Body.String() != test.body {
+			t.Errorf("%s: expected body: %q, got: %q", test.name, test.body, rec.Body.String())
+		}
+	}
+}
+
+func TestSecurityChallenge(t *testing.T) {
+	rec := httptest.NewRecorder()
+	securityHandler(SecurityRequirement{"bearer": {}})(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := rec.Header().Get("WWW-Authenticate"); got != "Bearer" {
+		t.Errorf("Expected challenge: %s, got: %s", "Bearer", got)
+	}
+}
+
+func TestSecurityUnconfiguredScheme(t *testing.T) {
+	handler := HandlerNoRequestNoResponse(func(ctx context.Context) error {
+		return nil
+	}, WithSecurity(SecurityRequirement{"oauth": {"read"}}))
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestKeyByPrincipal(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal any
+		key       string
+	}{
+		{"string", "alice", "alice"},
+		{"identified", &account{id: "42"}, "42"},
+		{"unidentified", &struct{ Name string }{"alice"}, "192.0.2.1"},
+		{"anonymous", nil, "192.0.2.1"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx := context.WithValue(req.Context(), authenticationKey{}, authentication{principal: test.principal})
+		if key := KeyByPrincipal(req.WithContext(ctx)); key != test.key {
+			t.Errorf("%s: expected key: %q, got: %q", test.name, test.key, key)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package gohandlr
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Date is a civil date without a time or location, the format: date of OpenAPI
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns the date of t in its location
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return Date{Year: year, Month: month, Day: day}
+}
+
+// ParseDate parses a date like 2006-01-02
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return Date{}, err
+	}
+	return DateOf(t), nil
+}
+
+// Time returns the start of the date in loc
+func (d Date) Time(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// IsZero reports whether d is the zero Date
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Date) UnmarshalText(text []byte) error {
+	date, err := ParseDate(string(text))
+	if err != nil {
+		return err
+	}
+	*d = date
+	return nil
+}
+
+// UUID is a universally unique identifier, the format: uuid of OpenAPI
+type UUID [16]byte
+
+// ParseUUID parses a UUID like 123e4567-e89b-12d3-a456-426614174000
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	digits := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36]
+	if _, err := hex.Decode(u[:], []byte(digits)); err != nil {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	return u, nil
+}
+
+// IsZero reports whether u is the nil UUID
+func (u UUID) IsZero() bool {
+	return u == UUID{}
+}
+
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *UUID) UnmarshalText(text []byte) error {
+	id, err := ParseUUID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = id
+	return nil
+}
+
+// ParseParam parses the value of a path, query, header or cookie parameter
+// into v, which points to a string, bool, number, []byte (base64), or a type
+// implementing encoding.TextUnmarshaler like time.Time, Date and UUID.
+func ParseParam(value string, v any) error {
+	if u, ok := v.(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("parameter target must be a non-nil pointer")
+	}
+	elem := rv.Elem()
+
+	// Optional parameters are pointers
+	if elem.Kind() == reflect.Pointer {
+		target := reflect.New(elem.Type().Elem())
+		if err := ParseParam(value, target.Interface()); err != nil {
+			return err
+		}
+		elem.Set(target)
+		return nil
+	}
+
+	switch elem.Kind() {
+	case reflect.String:
+		elem.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		elem.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, elem.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		elem.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, elem.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		elem.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, elem.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		elem.SetFloat(f)
+	case reflect.Slice:
+		if elem.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported parameter type %s", elem.Type())
+		}
+		b, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("invalid base64 %q", value)
+		}
+		elem.SetBytes(b)
+	default:
+		return fmt.Errorf("unsupported parameter type %s", elem.Type())
+	}
+	return nil
+}
+
+// FormatParam formats v as the value of a parameter, the way ParseParam
+// parses it
+func FormatParam(v any) (string, error) {
+	if m, ok := v.(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return string(text), err
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return "", errors.New("parameter value is nil")
+		}
+		return FormatParam(rv.Elem().Interface())
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits()), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(rv.Bytes()), nil
+		}
+	}
+	return "", fmt.Errorf("unsupported parameter type %s", rv.Type())
+}
@@ -0,0 +1,135 @@
+package gohandlr
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDate(t *testing.T) {
+	var v struct {
+		Born Date `json:"born"`
+	}
+	if err := json.Unmarshal([]byte(`{"born": "1990-07-04"}`), &v); err != nil {
+		t.Fatalf("Failed to unmarshal a date: %v", err)
+	}
+	if v.Born != (Date{1990, time.July, 4}) {
+		t.Errorf("Expected date: %s, got: %s", "1990-07-04", v.Born)
+	}
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"born":"1990-07-04"}` {
+		t.Errorf("Expected body: %s, got: %s", `{"born":"1990-07-04"}`, body)
+	}
+
+	if _, err := ParseDate("1990-13-04"); err == nil {
+		t.Errorf("Expected an invalid month to fail")
+	}
+}
+
+func TestUUID(t *testing.T) {
+	const s = "123e4567-e89b-12d3-a456-426614174000"
+	u, err := ParseUUID(s)
+	if err != nil {
+		t.Fatalf("Failed to parse a UUID: %v", err)
+	}
+	if u.String() != s {
+		t.Errorf("Expected UUID: %s, got: %s", s, u)
+	}
+
+	for _, invalid := range []string{"", "123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400g"} {
+		if _, err := ParseUUID(invalid); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestParseParam(t *testing.T) {
+	var (
+		s   string
+		i32 int32
+		i64 int64
+		f   float64
+		b   bool
+		ts  time.Time
+		d   Date
+		opt *int
+		raw []byte
+	)
+	tests := []struct {
+		value string
+		v     any
+	}{
+		{"gopher", &s},
+		{"-42", &i32},
+		{"9007199254740993", &i64},
+		{"2.5", &f},
+		{"true", &b},
+		{"2024-02-29T12:00:00Z", &ts},
+		{"2024-02-29", &d},
+		{"7", &opt},
+		{"aGk=", &raw},
+	}
+	for _, test := range tests {
+		if err := ParseParam(test.value, test.v); err != nil {
+			t.Errorf("Failed to parse %q: %v", test.value, err)
+		}
+	}
+	if s != "gopher" || i32 != -42 || i64 != 9007199254740993 || f != 2.5 || !b || ts.Day() != 29 || d.Day != 29 || opt == nil || *opt != 7 || string(raw) != "hi" {
+		t.Errorf("Expected the parsed values, got: %v %v %v %v %v %v %v %v %q", s, i32, i64, f, b, ts, d, opt, raw)
+	}
+
+	invalid := []struct {
+		value string
+		v     any
+	}{
+		{"3000000000", &i32},
+		{"maybe", &b},
+		{"1.5", &i64},
+		{"yesterday", &ts},
+	}
+	for _, test := range invalid {
+		if err := ParseParam(test.value, test.v); err == nil {
+			t.Errorf("Expected %q to be invalid for %T", test.value, test.v)
+		}
+	}
+}
+
+func TestFormatParam(t *testing.T) {
+	seven := 7
+	tests := []struct {
+		v        any
+		expected string
+	}{
+		{"gopher", "gopher"},
+		{int32(-42), "-42"},
+		{int64(9007199254740993), "9007199254740993"},
+		{2.5, "2.5"},
+		{float32(0.1), "0.1"},
+		{true, "true"},
+		{time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC), "2024-02-29T12:00:00Z"},
+		{Date{Year: 2024, Month: 2, Day: 29}, "2024-02-29"},
+		{&seven, "7"},
+		{[]byte("hi"), "aGk="},
+	}
+	for _, test := range tests {
+		value, err := FormatParam(test.v)
+		if err != nil {
+			t.Errorf("Failed to format %v: %v", test.v, err)
+			continue
+		}
+		if value != test.expected {
+			t.Errorf("Expected value: %s, got: %s", test.expected, value)
+		}
+	}
+
+	if _, err := FormatParam((*int)(nil)); err == nil {
+		t.Errorf("Expected a nil value to fail")
+	}
+	if _, err := FormatParam(struct{}{}); err == nil {
+		t.Errorf("Expected a struct to fail")
+	}
+}
@@ -0,0 +1,51 @@
+package gohandlr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// DecodeVariant decodes the JSON of a oneOf or anyOf union into v, one of
+// its variants. It fails when the JSON has properties v doesn't have or
+// lacks one of the required properties, so unions without a discriminator
+// can tell their variants apart by their shape.
+func DecodeVariant(data []byte, v any, required ...string) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if len(required) == 0 {
+		return nil
+	}
+
+	var properties map[string]json.RawMessage
+	if err := json.Unmarshal(data, &properties); err != nil {
+		return err
+	}
+	for _, name := range required {
+		if _, ok := properties[name]; !ok {
+			return fmt.Errorf("missing property %q", name)
+		}
+	}
+	return nil
+}
+
+// Discriminator returns the value of the discriminator property of a JSON
+// object, which names the variant of a union it is
+func Discriminator(data []byte, property string) (string, error) {
+	var properties map[string]json.RawMessage
+	if err := json.Unmarshal(data, &properties); err != nil {
+		return "", err
+	}
+	raw, ok := properties[property]
+	if !ok {
+		return "", fmt.Errorf("missing discriminator %q", property)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("invalid discriminator %q: %w", property, err)
+	}
+	return value, nil
+}
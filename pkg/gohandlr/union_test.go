@@ -0,0 +1,52 @@
+package gohandlr
+
+import (
+	"testing"
+)
+
+func TestDecodeVariant(t *testing.T) {
+	type cat struct {
+		Claws int  `json:"claws"`
+		Tame  bool `json:"tame"`
+	}
+
+	var c cat
+	if err := DecodeVariant([]byte(`{"claws": 10}`), &c, "claws"); err != nil {
+		t.Fatalf("Failed to decode a cat: %v", err)
+	}
+	if c.Claws != 10 {
+		t.Errorf("Expected claws: %d, got: %d", 10, c.Claws)
+	}
+
+	tests := map[string]string{
+		"unknown property": `{"claws": 10, "bark": true}`,
+		"missing property": `{"tame": true}`,
+		"wrong type":       `"cat"`,
+	}
+	for name, data := range tests {
+		if err := DecodeVariant([]byte(data), &c, "claws"); err == nil {
+			t.Errorf("Expected %s to fail", name)
+		}
+	}
+
+	var s string
+	if err := DecodeVariant([]byte(`"cat"`), &s); err != nil || s != "cat" {
+		t.Errorf("Expected string: %s, got: %s (%v)", "cat", s, err)
+	}
+}
+
+func TestDiscriminator(t *testing.T) {
+	kind, err := Discriminator([]byte(`{"petType": "cat", "claws": 10}`), "petType")
+	if err != nil {
+		t.Fatalf("Failed to read the discriminator: %v", err)
+	}
+	if kind != "cat" {
+		t.Errorf("Expected discriminator: %s, got: %s", "cat", kind)
+	}
+
+	for _, data := range []string{`{"claws": 10}`, `{"petType": 1}`, `[]`} {
+		if _, err := Discriminator([]byte(data), "petType"); err == nil {
+			t.Errorf("Expected %s to fail", data)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package gohandlr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+// OpenAPIValidator validates requests, and optionally responses, against an OpenAPI document
+type OpenAPIValidator struct {
+	router routers.Router
+	// Responses validates responses too. A response that doesn't match the
+	// document is logged and replaced with a 500 problem response, which
+	// makes drift obvious in development and tests.
+	Responses bool
+	// Logf reports response mismatches, log.Printf by default
+	Logf func(format string, args ...any)
+}
+
+// NewOpenAPIValidator creates an OpenAPIValidator for doc. Routes are matched
+// on the path alone, ignoring the servers of the document, as handlers are.
+func NewOpenAPIValidator(doc *openapi3.T) (*OpenAPIValidator, error) {
+	local := *doc
+	local.Servers = nil
+	router, err := legacy.NewRouter(&local)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIValidator{router: router, Logf: log.Printf}, nil
+}
+
+// LoadOpenAPIValidator creates an OpenAPIValidator from a JSON or YAML document
+func LoadOpenAPIValidator(spec []byte) (*OpenAPIValidator, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, err
+	}
+	return NewOpenAPIValidator(doc)
+}
+
+// WithOpenAPIValidation validates requests against the OpenAPI document of
+// the validator before they are read. Invalid parameters get a 400 problem
+// response and invalid bodies a 422 problem response.
+func WithOpenAPIValidation(validator *OpenAPIValidator) Option {
+	return func(c *Config) {
+		c.OpenAPIValidator = validator
+	}
+}
+
+// Problem is an RFC 9457 problem details response
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// WriteProblem writes a problem details response for status
+func WriteProblem(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	})
+}
+
+func (v *OpenAPIValidator) middleware(next http.HandlerFunc, config *Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := v.router.FindRoute(r)
+		if err != nil {
+			// Requests the document doesn't describe are left to the handler
+			next(w, r)
+			return
+		}
+
+		// Validate the decompressed body
+		if err := config.decodeBody(r); err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				// Security is checked by the handler
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			},
+		}
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			WriteProblem(w, requestErrorStatus(err), err.Error())
+			return
+		}
+
+		if !v.Responses {
+			next(w, r)
+			return
+		}
+
+		buf := &bufferedWriter{w: w}
+		next(buf, r)
+		if buf.status == 0 {
+			buf.status = http.StatusOK
+		}
+
+		// Not Modified responses have no body to check
+		if buf.status == http.StatusNotModified {
+			buf.flush()
+			return
+		}
+		if err := v.validateResponse(input, buf, config); err != nil {
+			v.Logf("gohandlr: response to %s %s does not match the OpenAPI document: %v", r.Method, r.URL.Path, err)
+			for _, name := range []string{"Content-Encoding", "Content-Length", "ETag"} {
+				w.Header().Del(name)
+			}
+			WriteProblem(w, http.StatusInternalServerError, "response does not match the OpenAPI document: "+err.Error())
+			return
+		}
+		buf.flush()
+	}
+}
+
+func (v *OpenAPIValidator) validateResponse(input *openapi3filter.RequestValidationInput, buf *bufferedWriter, config *Config) error {
+	header := buf.Header()
+	body := buf.buf.Bytes()
+
+	// Validate the body as it was before it was compressed
+	if encoding := header.Get("Content-Encoding"); encoding != "" {
+		encoder := config.encoder(encoding)
+		if encoder == nil {
+			return fmt.Errorf("unknown content encoding %q", encoding)
+		}
+		reader, err := encoder.Decode(bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		if body, err = io.ReadAll(reader); err != nil {
+			return err
+		}
+	}
+
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 buf.status,
+		Header:                 header,
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+		},
+	}
+	responseInput.SetBodyBytes(body)
+	return openapi3filter.ValidateResponse(input.Request.Context(), responseInput)
+}
+
+// requestErrorStatus is 422 for bodies that don't match their schema and 400 otherwise
+func requestErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	var requestErr *openapi3filter.RequestError
+	if errors.As(err, &requestErr) && requestErr.RequestBody != nil {
+		var schemaErr *openapi3.SchemaError
+		if errors.As(err, &schemaErr) {
+			return http.StatusUnprocessableEntity
+		}
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,137 @@
+package gohandlr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const validationSpec = `
+openapi: 3.0.3
+info:
+  title: Users
+  version: 1.0.0
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /users:
+    post:
+      parameters:
+        - name: dryRun
+          in: query
+          schema:
+            type: boolean
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+                  minLength: 1
+      responses:
+        "200":
+          description: Created
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [name]
+                properties:
+                  name:
+                    type: string
+                    minLength: 1
+`
+
+func validationHandler(t *testing.T, responses bool, user etagUser) http.HandlerFunc {
+	validator, err := LoadOpenAPIValidator([]byte(validationSpec))
+	if err != nil {
+		t.Fatalf("Failed to load the spec: %v", err)
+	}
+	validator.Responses = responses
+	validator.Logf = t.Logf
+
+	return HandlerWithRequestWithResponse(func(ctx context.Context, req *etagUser) (etagUser, error) {
+		return user, nil
+	}, WithOpenAPIValidation(validator))
+}
+
+func TestWithOpenAPIValidation(t *testing.T) {
+	handler := validationHandler(t, false, etagUser{Name: "gopher"})
+
+	tests := []struct {
+		target string
+		body   string
+		status int
+	}{
+		{"/users", `{"name": "gopher"}`, http.StatusOK},
+		{"/users?dryRun=maybe", `{"name": "gopher"}`, http.StatusBadRequest},
+		{"/users", `{"name": ""}`, http.StatusUnprocessableEntity},
+		{"/users", `{}`, http.StatusUnprocessableEntity},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, test.target, strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("Expected status code for %s %s: %d, got: %d", test.target, test.body, test.status, rec.Code)
+		}
+		if test.status == http.StatusOK {
+			continue
+		}
+
+		if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+			t.Errorf("Expected content type: %s, got: %s", "application/problem+json", got)
+		}
+		var problem Problem
+		if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+			t.Fatalf("Failed to decode the problem: %v", err)
+		}
+		if problem.Status != test.status || problem.Detail == "" {
+			t.Errorf("Expected a problem with status %d and a detail, got: %+v", test.status, problem)
+		}
+	}
+
+	// Paths the document doesn't describe reach the handler
+	req := httptest.NewRequest(http.MethodPost, "/teams", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code: %d, got: %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestOpenAPIValidationResponses(t *testing.T) {
+	tests := []struct {
+		user   etagUser
+		status int
+	}{
+		{etagUser{Name: "gopher"}, http.StatusOK},
+		{etagUser{}, http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		handler := validationHandler(t, true, test.user)
+
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name": "gopher"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("Expected status code for %+v: %d, got: %d", test.user, test.status, rec.Code)
+		}
+		if test.status == http.StatusInternalServerError && !strings.Contains(rec.Body.String(), "does not match the OpenAPI document") {
+			t.Errorf("Expected the mismatch in the body, got: %s", rec.Body.String())
+		}
+	}
+}